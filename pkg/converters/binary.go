@@ -0,0 +1,93 @@
+package converters
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/sqldriver"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// BinaryMode selects how BINARY values are returned.
+type BinaryMode string
+
+const (
+	// BinaryBase64 returns the bytes as a base64 encoded JSON string.
+	BinaryBase64 BinaryMode = "base64"
+	// BinaryHex returns the bytes as a lowercase hex string.
+	BinaryHex BinaryMode = "hex"
+	// BinaryUTF8 returns the bytes as text, replacing invalid UTF-8 sequences
+	// with the Unicode replacement character.
+	BinaryUTF8 BinaryMode = "utf8"
+	// BinaryLength returns only the number of bytes.
+	BinaryLength BinaryMode = "length"
+)
+
+var ErrorInvalidBinaryMode = errors.New("invalid binary mode. Expected base64, hex, utf8 or length")
+
+// ValidateBinaryMode accepts the known modes and the empty default.
+func ValidateBinaryMode(mode BinaryMode) error {
+	switch mode {
+	case "", BinaryBase64, BinaryHex, BinaryUTF8, BinaryLength:
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrorInvalidBinaryMode, mode)
+}
+
+// binaryConverter returns the BINARY converter of a mode, base64 when empty.
+func binaryConverter(mode BinaryMode) Converter {
+	switch mode {
+	case BinaryHex:
+		return binaryStringConverter(hex.EncodeToString)
+	case BinaryUTF8:
+		return binaryStringConverter(func(b []byte) string {
+			return strings.ToValidUTF8(string(b), "\uFFFD")
+		})
+	case BinaryLength:
+		return Converter{
+			fieldType: data.FieldTypeNullableInt64,
+			convert: func(in interface{}) (interface{}, error) {
+				b, ok, err := binaryBytes(in)
+				if !ok || err != nil {
+					return (*int64)(nil), err
+				}
+				n := int64(len(b))
+				return &n, nil
+			},
+		}
+	}
+	return Converter{
+		fieldType: data.FieldTypeNullableJSON,
+		convert:   jsonConverter,
+	}
+}
+
+func binaryStringConverter(render func([]byte) string) Converter {
+	return Converter{
+		fieldType: data.FieldTypeNullableString,
+		convert: func(in interface{}) (interface{}, error) {
+			b, ok, err := binaryBytes(in)
+			if !ok || err != nil {
+				return (*string)(nil), err
+			}
+			return makePtrToString(render(b)), nil
+		},
+	}
+}
+
+// binaryBytes returns the bytes of a scanned BINARY, false when it is NULL.
+func binaryBytes(in interface{}) ([]byte, bool, error) {
+	if in == nil {
+		return nil, false, nil
+	}
+	v, ok := in.(*sqldriver.Binary)
+	if !ok {
+		return nil, false, invalidType("BINARY")
+	}
+	if v.IsNull() {
+		return nil, false, nil
+	}
+	return *v, true, nil
+}
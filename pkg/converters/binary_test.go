@@ -0,0 +1,44 @@
+package converters
+
+import (
+	"testing"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/sqldriver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryModes(t *testing.T) {
+	value := sqldriver.Binary("caf\xc3\xa9\xff")
+	for _, tc := range []struct {
+		mode BinaryMode
+		want interface{}
+	}{
+		{mode: BinaryHex, want: "636166c3a9ff"},
+		{mode: BinaryUTF8, want: "café�"},
+		{mode: BinaryLength, want: int64(6)},
+	} {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			converter := binaryConverter(tc.mode)
+
+			v, err := converter.convert(&value)
+			require.NoError(t, err)
+			switch want := tc.want.(type) {
+			case string:
+				require.Equal(t, want, *v.(*string))
+			case int64:
+				require.Equal(t, want, *v.(*int64))
+			}
+
+			null := sqldriver.Binary(nil)
+			v, err = converter.convert(&null)
+			require.NoError(t, err)
+			require.Nil(t, v)
+		})
+	}
+}
+
+func TestValidateBinaryMode(t *testing.T) {
+	require.NoError(t, ValidateBinaryMode(""))
+	require.NoError(t, ValidateBinaryMode(BinaryUTF8))
+	require.ErrorIs(t, ValidateBinaryMode("octal"), ErrorInvalidBinaryMode)
+}
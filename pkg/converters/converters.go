@@ -4,9 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
-	"regexp"
+	"sort"
 	"time"
 
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/odpstype"
 	data2 "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
 	"github.com/aliyun/aliyun-odps-go-sdk/sqldriver"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
@@ -14,35 +15,25 @@ import (
 )
 
 type Converter struct {
-	convert    func(in interface{}) (interface{}, error)
-	fieldType  data.FieldType
-	matchRegex *regexp.Regexp
-	scanType   reflect.Type
+	convert   func(in interface{}) (interface{}, error)
+	fieldType data.FieldType
+	scanType  reflect.Type
 }
 
 func invalidType(name string) error {
 	return fmt.Errorf("invalid type - %s", name)
 }
 
-var matchRegexes = map[string]*regexp.Regexp{
-	"ARRAY":   regexp.MustCompile(`^ARRAY<.*>`),
-	"DECIMAL": regexp.MustCompile(`^DECIMAL`),
-	"VARCHAR": regexp.MustCompile(`^VARCHAR`),
-	"CHAR":    regexp.MustCompile(`^CHAR`),
-	"MAP":     regexp.MustCompile(`^MAP<.*>`),
-	"STRUCT":  regexp.MustCompile(`^STRUCT<.*>`),
-}
-
 var Converters = map[string]Converter{
 	"BIGINT": {
 		fieldType: data.FieldTypeNullableInt64,
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return int64(0), nil
+				return (*int64)(nil), nil
 			}
 
 			if v, ok := in.(int64); ok {
-				return v, nil
+				return &v, nil
 			}
 
 			if v, ok := in.(*sqldriver.NullInt64); ok {
@@ -59,11 +50,11 @@ var Converters = map[string]Converter{
 		fieldType: data.FieldTypeNullableInt32,
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return int32(0), nil
+				return (*int32)(nil), nil
 			}
 
 			if v, ok := in.(int32); ok {
-				return v, nil
+				return &v, nil
 			}
 
 			if v, ok := in.(*sqldriver.NullInt32); ok {
@@ -80,11 +71,11 @@ var Converters = map[string]Converter{
 		fieldType: data.FieldTypeNullableInt16,
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return int16(0), nil
+				return (*int16)(nil), nil
 			}
 
 			if v, ok := in.(int16); ok {
-				return v, nil
+				return &v, nil
 			}
 
 			if v, ok := in.(*sqldriver.NullInt16); ok {
@@ -101,11 +92,11 @@ var Converters = map[string]Converter{
 		fieldType: data.FieldTypeNullableInt8,
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return int8(0), nil
+				return (*int8)(nil), nil
 			}
 
 			if v, ok := in.(int8); ok {
-				return v, nil
+				return &v, nil
 			}
 
 			if v, ok := in.(*sqldriver.NullInt8); ok {
@@ -122,11 +113,11 @@ var Converters = map[string]Converter{
 		fieldType: data.FieldTypeNullableFloat64,
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return float64(0), nil
+				return (*float64)(nil), nil
 			}
 
 			if v, ok := in.(float64); ok {
-				return v, nil
+				return &v, nil
 			}
 
 			if v, ok := in.(*sqldriver.NullFloat64); ok {
@@ -143,11 +134,11 @@ var Converters = map[string]Converter{
 		fieldType: data.FieldTypeNullableFloat32,
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return float32(0), nil
+				return (*float32)(nil), nil
 			}
 
 			if v, ok := in.(float32); ok {
-				return v, nil
+				return &v, nil
 			}
 
 			if v, ok := in.(*sqldriver.NullFloat32); ok {
@@ -165,28 +156,23 @@ var Converters = map[string]Converter{
 		convert:   stringConverter,
 	},
 	"CHAR": {
-		fieldType:  data.FieldTypeNullableString,
-		matchRegex: matchRegexes["CHAR"],
-		convert:    stringConverter,
+		fieldType: data.FieldTypeNullableString,
+		convert:   stringConverter,
 	},
 	"VARCHAR": {
-		fieldType:  data.FieldTypeNullableString,
-		matchRegex: matchRegexes["VARCHAR"],
-		convert:    stringConverter,
-	},
-	"BINARY": {
-		fieldType: data.FieldTypeNullableJSON,
-		convert:   jsonConverter,
+		fieldType: data.FieldTypeNullableString,
+		convert:   stringConverter,
 	},
+	"BINARY": binaryConverter(BinaryBase64),
 	"BOOLEAN": {
 		fieldType: data.FieldTypeNullableBool,
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return false, nil
+				return (*bool)(nil), nil
 			}
 
 			if v, ok := in.(bool); ok {
-				return v, nil
+				return &v, nil
 			}
 
 			if v, ok := in.(*sqldriver.NullBool); ok {
@@ -203,7 +189,7 @@ var Converters = map[string]Converter{
 		fieldType: data.FieldTypeNullableTime,
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return time.Time{}, nil
+				return (*time.Time)(nil), nil
 			}
 
 			if v, ok := in.(*sqldriver.NullDate); ok {
@@ -220,14 +206,15 @@ var Converters = map[string]Converter{
 		fieldType: data.FieldTypeNullableTime,
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return time.Time{}, nil
+				return (*time.Time)(nil), nil
 			}
 
 			if v, ok := in.(*sqldriver.NullDateTime); ok {
 				if v.IsNull() {
 					return (*time.Time)(nil), nil
 				}
-				return &v.Time, nil
+				t := v.Time.UTC()
+				return &t, nil
 			}
 
 			return nil, invalidType("DATETIME")
@@ -237,26 +224,26 @@ var Converters = map[string]Converter{
 		fieldType: data.FieldTypeNullableTime,
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return time.Time{}, nil
+				return (*time.Time)(nil), nil
 			}
 
 			if v, ok := in.(*sqldriver.NullTimeStamp); ok {
 				if v.IsNull() {
 					return (*time.Time)(nil), nil
 				}
-				return &v.Time, nil
+				t := v.Time.UTC()
+				return &t, nil
 			}
 
 			return nil, invalidType("TIMESTAMP")
 		},
 	},
 	"DECIMAL": {
-		fieldType:  data.FieldTypeNullableString,
-		scanType:   reflect.TypeOf(sqldriver.Decimal{}),
-		matchRegex: matchRegexes["DECIMAL"],
+		fieldType: data.FieldTypeNullableString,
+		scanType:  reflect.TypeOf(sqldriver.Decimal{}),
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return "", nil
+				return (*string)(nil), nil
 			}
 
 			if v, ok := in.(*sqldriver.Decimal); ok {
@@ -270,124 +257,174 @@ var Converters = map[string]Converter{
 		},
 	},
 	"MAP": {
-		fieldType:  data.FieldTypeNullableString,
-		scanType:   reflect.TypeOf(sqldriver.Map{}),
-		matchRegex: matchRegexes["MAP"],
+		fieldType: data.FieldTypeNullableJSON,
+		scanType:  reflect.TypeOf(sqldriver.Map{}),
+		convert:   nestedJSON("MAP"),
+	},
+	"ARRAY": {
+		fieldType: data.FieldTypeNullableJSON,
+		scanType:  reflect.TypeOf(sqldriver.Array{}),
+		convert:   nestedJSON("ARRAY"),
+	},
+	"STRUCT": {
+		fieldType: data.FieldTypeNullableJSON,
+		scanType:  reflect.TypeOf(sqldriver.Struct{}),
+		convert:   nestedJSON("STRUCT"),
+	},
+	"VOID": {
+		fieldType: data.FieldTypeNullableBool,
+		scanType:  reflect.TypeOf(data2.Null),
+		convert: func(in interface{}) (interface{}, error) {
+			return (*bool)(nil), nil
+		},
+	},
+	"INTERVAL_DAY_TIME":   intervalDayTimeConverter(IntervalString),
+	"INTERVAL_YEAR_MONTH": intervalYearMonthConverter(IntervalString),
+	"JSON":                jsonTypeConverter,
+	"TIMESTAMP_NTZ":       timestampNTZConverter(nil),
+}
+
+// nestedStringConverters render ARRAY, MAP and STRUCT values as ODPS literal
+// strings such as map('k1', 'v1'), the format used before nested values became JSON.
+var nestedStringConverters = map[string]Converter{
+	"MAP": {
+		fieldType: data.FieldTypeNullableString,
+		scanType:  reflect.TypeOf(sqldriver.Map{}),
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return "", nil
+				return (*string)(nil), nil
 			}
 
 			if v, ok := in.(*sqldriver.Map); ok {
-				return makePtrToString(v.String()), nil
+				if v.IsNull() {
+					return (*string)(nil), nil
+				}
+				return makePtrToString(literal((*data2.Map)(v))), nil
 			}
 
 			return nil, invalidType("MAP")
 		},
 	},
 	"ARRAY": {
-		fieldType:  data.FieldTypeNullableString,
-		matchRegex: matchRegexes["ARRAY"],
-		scanType:   reflect.TypeOf(sqldriver.Array{}),
+		fieldType: data.FieldTypeNullableString,
+		scanType:  reflect.TypeOf(sqldriver.Array{}),
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return "", nil
+				return (*string)(nil), nil
 			}
 
 			if v, ok := in.(*sqldriver.Array); ok {
-				return makePtrToString(v.String()), nil
+				if v.IsNull() {
+					return (*string)(nil), nil
+				}
+				return makePtrToString(literal((*data2.Array)(v))), nil
 			}
 
 			return nil, invalidType("ARRAY")
 		},
 	},
 	"STRUCT": {
-		fieldType:  data.FieldTypeNullableString,
-		scanType:   reflect.TypeOf(sqldriver.Struct{}),
-		matchRegex: matchRegexes["STRUCT"],
+		fieldType: data.FieldTypeNullableString,
+		scanType:  reflect.TypeOf(sqldriver.Struct{}),
 		convert: func(in interface{}) (interface{}, error) {
 			if in == nil {
-				return "", nil
+				return (*string)(nil), nil
 			}
 
 			if v, ok := in.(*sqldriver.Struct); ok {
-				return makePtrToString(v.String()), nil
+				if v.IsNull() {
+					return (*string)(nil), nil
+				}
+				return makePtrToString(literal((*data2.Struct)(v))), nil
 			}
 
 			return nil, invalidType("STRUCT")
 		},
 	},
-	"VOID": {
-		fieldType: data.FieldTypeNullableBool,
-		scanType:  reflect.TypeOf(data2.Null),
-		convert: func(in interface{}) (interface{}, error) {
-			return nil, nil
-		},
-	},
-	"INTERVAL_DAY_TIME": {
-		fieldType: data.FieldTypeNullableString,
-		scanType:  reflect.TypeOf(data2.IntervalDayTime{}),
-		convert: func(in interface{}) (interface{}, error) {
-			if in == nil {
-				return "", nil
-			}
-
-			if v, ok := in.(*data2.IntervalDayTime); ok {
-				return makePtrToString(v.String()), nil
-			}
+}
 
-			return nil, invalidType("INTERVAL_DAY_TIME")
-		},
-	},
-	"INTERVAL_YEAR_MONTH": {
-		fieldType: data.FieldTypeNullableString,
-		scanType:  reflect.TypeOf(data2.IntervalYearMonth(0)),
-		convert: func(in interface{}) (interface{}, error) {
-			if in == nil {
-				return "", nil
-			}
+// Options selects between the available renderings of a type.
+type Options struct {
+	// NestedAsString renders ARRAY, MAP and STRUCT values as ODPS literal strings instead of JSON.
+	NestedAsString bool
+	// FlattenStructs expands STRUCT columns into one field per nested field.
+	FlattenStructs bool
+	// Explode names an ARRAY or MAP column to expand into one row per element.
+	Explode string
+	// DecimalMode selects how DECIMAL values are returned, strings by default.
+	DecimalMode DecimalMode
+	// IntervalMode selects how INTERVAL values are returned, strings by default.
+	IntervalMode IntervalMode
+	// BinaryMode selects how BINARY values are returned, base64 JSON strings by default.
+	BinaryMode BinaryMode
+	// NTZLocation is the timezone TIMESTAMP_NTZ wall clocks are read in, UTC when nil.
+	NTZLocation *time.Location
+}
 
-			if v, ok := in.(*data2.IntervalDayTime); ok {
-				return (makePtrToString)(v.String()), nil
-			}
+// ConvertersFor returns the converters rendering values with the given
+// options, sorted by type name.
+func ConvertersFor(opts Options) []sqlutil.Converter {
+	converters := convertersFor(opts)
+	names := make([]string, 0, len(converters))
+	for name := range converters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-			return nil, invalidType("INTERVAL_YEAR_MONTH")
-		},
-	},
+	list := make([]sqlutil.Converter, len(names))
+	for i, name := range names {
+		list[i] = createConverter(name, converters[name])
+	}
+	return list
 }
 
-func GetConverter(cn string) sqlutil.Converter {
-	converter, ok := Converters[cn]
-	if ok {
-		return createConverter(cn, converter)
-	}
+// convertersFor returns the converters for the options by type name.
+func convertersFor(opts Options) map[string]Converter {
+	converters := make(map[string]Converter, len(Converters))
 	for name, converter := range Converters {
-		if name == cn {
-			return createConverter(name, converter)
-		}
-		if converter.matchRegex != nil && converter.matchRegex.MatchString(cn) {
-			return createConverter(name, converter)
+		converters[name] = converter
+	}
+	if opts.NestedAsString {
+		for name, converter := range nestedStringConverters {
+			converters[name] = converter
 		}
 	}
-	return sqlutil.Converter{}
+	converters["DECIMAL"] = decimalConverter(opts.DecimalMode, -1)
+	converters["INTERVAL_DAY_TIME"] = intervalDayTimeConverter(opts.IntervalMode)
+	converters["INTERVAL_YEAR_MONTH"] = intervalYearMonthConverter(opts.IntervalMode)
+	converters["BINARY"] = binaryConverter(opts.BinaryMode)
+	converters["TIMESTAMP_NTZ"] = timestampNTZConverter(opts.NTZLocation)
+	return converters
+}
+
+// GetConverter returns the converter of a type string such as DECIMAL(10,2)
+// for the options, selected by the name of the parsed type and matching
+// exactly that type string.
+func GetConverter(cn string, opts Options) sqlutil.Converter {
+	typ, err := odpstype.Parse(cn)
+	if err != nil {
+		return sqlutil.Converter{}
+	}
+	converters := convertersFor(opts)
+	if _, ok := converters[typ.Name]; !ok {
+		return sqlutil.Converter{}
+	}
+	converter := converterFor(converters, typ, opts)
+	converter.InputTypeName = cn
+	return converter
 }
 
 var MaxComputeConverters = MaxcomputeConverters()
 
 func MaxcomputeConverters() []sqlutil.Converter {
-	list := make([]sqlutil.Converter, 0, len(Converters))
-	for name, converter := range Converters {
-		list = append(list, createConverter(name, converter))
-	}
-	return list
+	return ConvertersFor(Options{})
 }
 
 func createConverter(name string, converter Converter) sqlutil.Converter {
 	return sqlutil.Converter{
-		Name:           name,
-		InputScanType:  converter.scanType,
-		InputTypeRegex: converter.matchRegex,
-		InputTypeName:  name,
+		Name:          name,
+		InputScanType: converter.scanType,
+		InputTypeName: name,
 		FrameConverter: sqlutil.FrameConverter{
 			FieldType:     converter.fieldType,
 			ConverterFunc: converter.convert,
@@ -397,11 +434,11 @@ func createConverter(name string, converter Converter) sqlutil.Converter {
 
 func stringConverter(in interface{}) (interface{}, error) {
 	if in == nil {
-		return "", nil
+		return (*string)(nil), nil
 	}
 
 	if v, ok := in.(string); ok {
-		return v, nil
+		return &v, nil
 	}
 
 	if v, ok := in.(*sqldriver.NullString); ok {
@@ -416,7 +453,10 @@ func stringConverter(in interface{}) (interface{}, error) {
 
 func jsonConverter(in interface{}) (interface{}, error) {
 	if in == nil {
-		return (*string)(nil), nil
+		return (*json.RawMessage)(nil), nil
+	}
+	if v, ok := in.(*sqldriver.Binary); ok && v.IsNull() {
+		return (*json.RawMessage)(nil), nil
 	}
 	jBytes, err := json.Marshal(in)
 	if err != nil {
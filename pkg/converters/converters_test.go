@@ -1 +1,175 @@
-package converters_test
\ No newline at end of file
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	data2 "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/datatype"
+	"github.com/aliyun/aliyun-odps-go-sdk/sqldriver"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+	"github.com/stretchr/testify/require"
+)
+
+func converterNamed(t *testing.T, converters []sqlutil.Converter, name string) sqlutil.Converter {
+	t.Helper()
+	for _, c := range converters {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("no converter for %s", name)
+	return sqlutil.Converter{}
+}
+
+func convert(t *testing.T, c sqlutil.Converter, in interface{}) interface{} {
+	t.Helper()
+	out, err := c.FrameConverter.ConverterFunc(in)
+	require.NoError(t, err)
+	return out
+}
+
+func requireJSON(t *testing.T, want string, out interface{}) {
+	t.Helper()
+	msg, ok := out.(*json.RawMessage)
+	require.True(t, ok, "got %T", out)
+	require.NotNil(t, msg)
+	require.JSONEq(t, want, string(*msg))
+}
+
+func TestNestedJSON(t *testing.T) {
+	list := ConvertersFor(Options{})
+	point := datatype.NewStructType(
+		datatype.NewStructFieldType("y", datatype.BigIntType),
+		datatype.NewStructFieldType("x", datatype.BigIntType),
+	)
+
+	array := data2.NewArrayWithType(datatype.NewArrayType(point))
+	first := data2.NewStructWithTyp(point)
+	require.NoError(t, first.SetField("y", data2.BigInt(2)))
+	require.NoError(t, first.SetField("x", data2.BigInt(1)))
+	array.UnSafeAppend(first, nil)
+
+	c := converterNamed(t, list, "ARRAY")
+	require.Equal(t, data.FieldTypeNullableJSON, c.FrameConverter.FieldType)
+	out := convert(t, c, (*sqldriver.Array)(array))
+	requireJSON(t, `[{"y":2,"x":1},null]`, out)
+	require.Equal(t, `[{"y":2,"x":1},null]`, string(*out.(*json.RawMessage)), "struct fields keep their order")
+
+	m := data2.NewMapWithType(datatype.NewMapType(datatype.StringType, datatype.NewDecimalType(38, 18)))
+	require.NoError(t, m.Set(data2.String("exact"), data2.NewDecimal(38, 18, "12345678901234567.123456789012345678")))
+	require.NoError(t, m.Set(data2.String("missing"), data2.Null))
+	out = convert(t, converterNamed(t, list, "MAP"), (*sqldriver.Map)(m))
+	requireJSON(t, `{"exact":12345678901234567.123456789012345678,"missing":null}`, out)
+	require.Contains(t, string(*out.(*json.RawMessage)), "12345678901234567.123456789012345678", "decimals keep every digit")
+
+	keys := data2.NewMapWithType(datatype.NewMapType(datatype.BigIntType, datatype.StringType))
+	require.NoError(t, keys.Set(data2.BigInt(1), data2.String("a")))
+	requireJSON(t, `{"1":"a"}`, convert(t, converterNamed(t, list, "MAP"), (*sqldriver.Map)(keys)))
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	nested := data2.NewArrayWithType(datatype.NewArrayType(datatype.StringType))
+	nested.UnSafeAppend(data2.String("a"))
+	s := data2.NewStructWithTyp(datatype.StructType{})
+	require.NoError(t, s.SetField("date", data2.Date(day)))
+	require.NoError(t, s.SetField("datetime", data2.DateTime(day.Add(90*time.Minute))))
+	require.NoError(t, s.SetField("timestamp", data2.Timestamp(day.Add(123456789))))
+	require.NoError(t, s.SetField("binary", data2.Binary("hi")))
+	require.NoError(t, s.SetField("bool", data2.Bool(true)))
+	require.NoError(t, s.SetField("double", data2.Double(1.5)))
+	require.NoError(t, s.SetField("varchar", data2.VarChar{}))
+	require.NoError(t, s.SetField("null", data2.Null))
+	require.NoError(t, s.SetField("list", nested))
+	requireJSON(t, `{
+		"date": "2024-03-01",
+		"datetime": "2024-03-01T01:30:00Z",
+		"timestamp": "2024-03-01T00:00:00.123456789Z",
+		"binary": "aGk=",
+		"bool": true,
+		"double": 1.5,
+		"varchar": "",
+		"null": null,
+		"list": ["a"]
+	}`, convert(t, converterNamed(t, list, "STRUCT"), (*sqldriver.Struct)(s)))
+
+	null := convert(t, c, &sqldriver.Array{})
+	require.Nil(t, null.(*json.RawMessage))
+}
+
+func TestNestedAsString(t *testing.T) {
+	list := ConvertersFor(Options{NestedAsString: true})
+	s := data2.NewStructWithTyp(datatype.StructType{})
+	require.NoError(t, s.SetField("x", data2.BigInt(1)))
+	require.NoError(t, s.SetField("y", data2.BigInt(2)))
+
+	c := converterNamed(t, list, "STRUCT")
+	require.Equal(t, data.FieldTypeNullableString, c.FrameConverter.FieldType)
+	out := convert(t, c, (*sqldriver.Struct)(s))
+	require.Equal(t, "struct<x:1,y:2>", *out.(*string))
+}
+
+func TestGetConverter(t *testing.T) {
+	for _, tc := range []struct {
+		typeName string
+		want     string
+	}{
+		{typeName: "BIGINT", want: "BIGINT"},
+		{typeName: "VARCHAR(4)", want: "VARCHAR"},
+		{typeName: "CHAR(4)", want: "CHAR"},
+		{typeName: "decimal(38, 18)", want: "DECIMAL"},
+		{typeName: "DATETIME", want: "DATETIME"},
+		{typeName: "TIMESTAMP", want: "TIMESTAMP"},
+		{typeName: "ARRAY<STRUCT<a:INT>>", want: "ARRAY"},
+		{typeName: "MAP<STRING,ARRAY<BIGINT>>", want: "MAP"},
+		{typeName: "STRUCT<`a b`:MAP<STRING,INT>>", want: "STRUCT"},
+		{typeName: "UNKNOWN_TYPE", want: ""},
+		{typeName: "ARRAY<", want: ""},
+	} {
+		t.Run(tc.typeName, func(t *testing.T) {
+			require.Equal(t, tc.want, GetConverter(tc.typeName, Options{}).Name)
+		})
+	}
+
+	// the parameters of the type select the converter
+	c := GetConverter("DECIMAL(10,2)", Options{DecimalMode: DecimalScaled})
+	require.Equal(t, "DECIMAL(10,2)", c.InputTypeName)
+	require.Equal(t, data.FieldTypeNullableInt64, c.FrameConverter.FieldType)
+	out, err := c.FrameConverter.ConverterFunc((*sqldriver.Decimal)(data2.NewDecimal(10, 2, "1.234")))
+	require.NoError(t, err)
+	require.Equal(t, int64(123), *out.(*int64))
+}
+
+func TestConvertersForOrder(t *testing.T) {
+	list := ConvertersFor(Options{})
+	for i := 1; i < len(list); i++ {
+		require.Less(t, list[i-1].Name, list[i].Name)
+	}
+}
+
+func TestConvertersNil(t *testing.T) {
+	for _, opts := range []Options{
+		{},
+		{NestedAsString: true},
+		{DecimalMode: DecimalFloat},
+		{DecimalMode: DecimalScaled},
+		{IntervalMode: IntervalNumeric},
+		{BinaryMode: BinaryHex},
+		{BinaryMode: BinaryUTF8},
+		{BinaryMode: BinaryLength},
+	} {
+		for _, c := range ConvertersFor(opts) {
+			t.Run(fmt.Sprintf("%s %+v", c.Name, opts), func(t *testing.T) {
+				v, err := c.FrameConverter.ConverterFunc(nil)
+				require.NoError(t, err)
+
+				field := data.NewFieldFromFieldType(c.FrameConverter.FieldType, 0)
+				require.NotPanics(t, func() { field.Append(v) })
+				_, ok := field.ConcreteAt(0)
+				require.False(t, ok, "a nil value converts to a null")
+			})
+		}
+	}
+}
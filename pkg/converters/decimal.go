@@ -0,0 +1,165 @@
+package converters
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/odpstype"
+	data2 "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/sqldriver"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// DecimalMode selects how DECIMAL values are returned.
+type DecimalMode string
+
+const (
+	// DecimalString keeps every digit of the value as a string.
+	DecimalString DecimalMode = "string"
+	// DecimalFloat returns a float64, which graphs and reducers can use but
+	// which rounds values with more than 15 significant digits.
+	DecimalFloat DecimalMode = "float"
+	// DecimalScaled returns the value multiplied by 10^scale and rounded as an
+	// int64, e.g. cents for a DECIMAL(18,2) amount. Values out of the int64
+	// range are returned as null.
+	DecimalScaled DecimalMode = "scaled"
+)
+
+// float64Digits is the number of significant decimal digits a float64 keeps.
+const float64Digits = 15
+
+var (
+	ErrorInvalidDecimalMode = errors.New("invalid decimal mode. Expected string, float or scaled")
+	ErrorDecimalOverflow    = errors.New("decimal does not fit in a scaled int64")
+)
+
+// ValidateDecimalMode accepts the known modes and the empty default.
+func ValidateDecimalMode(mode DecimalMode) error {
+	switch mode {
+	case "", DecimalString, DecimalFloat, DecimalScaled:
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrorInvalidDecimalMode, mode)
+}
+
+// ParseDecimalType reads the precision and scale of a DECIMAL(p,s) type name.
+// A DECIMAL declared without them is DECIMAL(38,18).
+func ParseDecimalType(name string) (precision, scale int, ok bool) {
+	typ, err := odpstype.Parse(name)
+	if err != nil {
+		return 0, 0, false
+	}
+	return typ.DecimalParams()
+}
+
+// decimalConverter converts DECIMAL values for the mode. A negative scale
+// scales each value by its own scale, for when the declared type is unknown.
+func decimalConverter(mode DecimalMode, scale int) Converter {
+	converter := Converter{
+		scanType: reflect.TypeOf(sqldriver.Decimal{}),
+	}
+
+	switch mode {
+	case DecimalFloat:
+		converter.fieldType = data.FieldTypeNullableFloat64
+		converter.convert = func(in interface{}) (interface{}, error) {
+			if in == nil {
+				return (*float64)(nil), nil
+			}
+			d, ok := in.(*sqldriver.Decimal)
+			if !ok {
+				return nil, invalidType("DECIMAL")
+			}
+			v := (*data2.Decimal)(d)
+			if d.IsNull() || v.Value() == "" {
+				return (*float64)(nil), nil
+			}
+			f, err := strconv.ParseFloat(v.Value(), 64)
+			if err != nil {
+				return nil, err
+			}
+			return &f, nil
+		}
+	case DecimalScaled:
+		converter.fieldType = data.FieldTypeNullableInt64
+		converter.convert = func(in interface{}) (interface{}, error) {
+			if in == nil {
+				return (*int64)(nil), nil
+			}
+			d, ok := in.(*sqldriver.Decimal)
+			if !ok {
+				return nil, invalidType("DECIMAL")
+			}
+			v := (*data2.Decimal)(d)
+			if d.IsNull() || v.Value() == "" {
+				return (*int64)(nil), nil
+			}
+			s := scale
+			if s < 0 {
+				s = v.Scale()
+			}
+			i, err := scaleDecimal(v.Value(), s)
+			if err != nil {
+				return nil, err
+			}
+			return &i, nil
+		}
+	default:
+		converter = Converters["DECIMAL"]
+	}
+
+	return converter
+}
+
+// scaleDecimal shifts the decimal point of value by scale digits, rounding the
+// digits beyond the scale half away from zero.
+func scaleDecimal(value string, scale int) (int64, error) {
+	negative := strings.HasPrefix(value, "-")
+	integer, fraction, _ := strings.Cut(strings.TrimLeft(value, "+-"), ".")
+	round := len(fraction) > scale && fraction[scale] >= '5'
+	if len(fraction) > scale {
+		fraction = fraction[:scale]
+	}
+	fraction += strings.Repeat("0", scale-len(fraction))
+
+	i, ok := new(big.Int).SetString("0"+integer+fraction, 10)
+	if !ok {
+		return 0, fmt.Errorf("invalid decimal: %s", value)
+	}
+	if round {
+		i.Add(i, big.NewInt(1))
+	}
+	if negative {
+		i.Neg(i)
+	}
+	if !i.IsInt64() {
+		return 0, fmt.Errorf("%w: %s", ErrorDecimalOverflow, value)
+	}
+	return i.Int64(), nil
+}
+
+// decimalField sets the display decimals of a DECIMAL field to its scale and
+// returns a notice when the float mode may lose digits of the declared
+// precision. A DECIMAL declared without a precision gets no notice.
+func decimalField(field *data.Field, typ *odpstype.Type, mode DecimalMode) *data.Notice {
+	precision, scale, ok := typ.DecimalParams()
+	if !ok {
+		return nil
+	}
+
+	decimals := uint16(scale)
+	field.SetConfig(&data.FieldConfig{Decimals: &decimals})
+
+	if mode != DecimalFloat || len(typ.Params) == 0 || precision <= float64Digits {
+		return nil
+	}
+	return &data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text: fmt.Sprintf("%s is %s, values with more than %d significant digits are rounded to float64. Use the string decimal mode to keep every digit.",
+			field.Name, typ, float64Digits),
+	}
+}
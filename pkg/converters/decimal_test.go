@@ -0,0 +1,138 @@
+package converters
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/odpstest"
+	data2 "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDecimalType(t *testing.T) {
+	tests := []struct {
+		name      string
+		precision int
+		scale     int
+		ok        bool
+	}{
+		{name: "DECIMAL(10,2)", precision: 10, scale: 2, ok: true},
+		{name: "DECIMAL(38, 18)", precision: 38, scale: 18, ok: true},
+		{name: "DECIMAL", precision: 38, scale: 18, ok: true},
+		{name: "DOUBLE"},
+		{name: "ARRAY<DECIMAL(10,2)>"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			precision, scale, ok := ParseDecimalType(tc.name)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.precision, precision)
+			require.Equal(t, tc.scale, scale)
+		})
+	}
+}
+
+func TestDecimalModes(t *testing.T) {
+	result := odpstest.Result{
+		Columns: []odpstest.Column{{Name: "amount", Type: "DECIMAL(10,2)"}, {Name: "ratio", Type: "DECIMAL(38,18)"}},
+		Rows: [][]driver.Value{
+			{data2.NewDecimal(10, 2, "1234.5"), data2.NewDecimal(38, 18, "0.123456789012345678")},
+			{data2.NewDecimal(10, 2, "-0.07"), nil},
+		},
+	}
+
+	values := func(f *data.Field) []interface{} {
+		res := make([]interface{}, f.Len())
+		for i := range res {
+			if v, ok := f.ConcreteAt(i); ok {
+				res[i] = v
+			}
+		}
+		return res
+	}
+
+	t.Run("string", func(t *testing.T) {
+		frame, err := FrameFromRows(odpstest.Query(t, result), Options{})
+		require.NoError(t, err)
+		require.Equal(t, data.FieldTypeNullableString, frame.Fields[0].Type())
+		require.Equal(t, []interface{}{"1234.5", "-0.07"}, values(frame.Fields[0]))
+		require.Equal(t, uint16(2), *frame.Fields[0].Config.Decimals)
+		require.Nil(t, frame.Meta)
+	})
+
+	t.Run("float", func(t *testing.T) {
+		frame, err := FrameFromRows(odpstest.Query(t, result), Options{DecimalMode: DecimalFloat})
+		require.NoError(t, err)
+		require.Equal(t, data.FieldTypeNullableFloat64, frame.Fields[0].Type())
+		require.Equal(t, []interface{}{1234.5, -0.07}, values(frame.Fields[0]))
+		require.Equal(t, uint16(2), *frame.Fields[0].Config.Decimals)
+		require.Equal(t, uint16(18), *frame.Fields[1].Config.Decimals)
+		require.Equal(t, []interface{}{0.123456789012345678, nil}, values(frame.Fields[1]))
+
+		require.Len(t, frame.Meta.Notices, 1)
+		require.Contains(t, frame.Meta.Notices[0].Text, "ratio is DECIMAL(38,18)")
+	})
+
+	t.Run("scaled", func(t *testing.T) {
+		frame, err := FrameFromRows(odpstest.Query(t, result), Options{DecimalMode: DecimalScaled})
+		require.NoError(t, err)
+		require.Equal(t, data.FieldTypeNullableInt64, frame.Fields[0].Type())
+		require.Equal(t, []interface{}{int64(123450), int64(-7)}, values(frame.Fields[0]))
+		require.Equal(t, []interface{}{int64(123456789012345678), nil}, values(frame.Fields[1]))
+		require.Equal(t, uint16(2), *frame.Fields[0].Config.Decimals)
+		require.Equal(t, uint16(18), *frame.Fields[1].Config.Decimals)
+		require.Nil(t, frame.Meta)
+	})
+
+	t.Run("float without a declared precision", func(t *testing.T) {
+		result := odpstest.Result{
+			Columns: []odpstest.Column{{Name: "ratio", Type: "DECIMAL"}},
+			Rows:    [][]driver.Value{{data2.NewDecimal(38, 18, "1.5")}},
+		}
+		frame, err := FrameFromRows(odpstest.Query(t, result), Options{DecimalMode: DecimalFloat})
+		require.NoError(t, err)
+		require.Equal(t, uint16(18), *frame.Fields[0].Config.Decimals)
+		require.Nil(t, frame.Meta)
+	})
+
+	t.Run("scaled overflow", func(t *testing.T) {
+		result := odpstest.Result{
+			Columns: []odpstest.Column{{Name: "ratio", Type: "DECIMAL"}},
+			Rows: [][]driver.Value{
+				{data2.NewDecimal(38, 18, "1.5")},
+				{data2.NewDecimal(38, 18, "12.5")},
+			},
+		}
+		frame, err := FrameFromRows(odpstest.Query(t, result), Options{DecimalMode: DecimalScaled})
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{int64(1500000000000000000), nil}, values(frame.Fields[0]))
+		require.Len(t, frame.Meta.Notices, 1)
+		require.Equal(t, data.NoticeSeverityWarning, frame.Meta.Notices[0].Severity)
+		require.Contains(t, frame.Meta.Notices[0].Text, "Values of ratio that do not fit in a scaled int64 are returned as null (1 rows)")
+	})
+}
+
+func TestScaleDecimal(t *testing.T) {
+	i, err := scaleDecimal("12.3456", 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(1235), i)
+
+	i, err = scaleDecimal("-0.125", 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(-13), i)
+
+	i, err = scaleDecimal("0.124", 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(12), i)
+
+	i, err = scaleDecimal("-5", 3)
+	require.NoError(t, err)
+	require.Equal(t, int64(-5000), i)
+
+	_, err = scaleDecimal("123456789012345678901", 0)
+	require.ErrorIs(t, err, ErrorDecimalOverflow)
+
+	require.NoError(t, ValidateDecimalMode(""))
+	require.ErrorIs(t, ValidateDecimalMode("double"), ErrorInvalidDecimalMode)
+}
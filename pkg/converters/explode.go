@@ -0,0 +1,141 @@
+package converters
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/odpstype"
+	data2 "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/sqldriver"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+)
+
+var (
+	ErrorUnknownExplodeColumn = errors.New("explode column not found in the result")
+	ErrorNotExplodable        = errors.New("explode column is not an ARRAY or MAP")
+)
+
+// entry is an element of an exploded column with its index or key.
+type entry struct {
+	key   data2.Data
+	value data2.Data
+}
+
+// explodeColumn turns an ARRAY column into index and value fields, or a MAP
+// column into key and value fields, with one row per element. Rows where the
+// column is null or empty are kept with null key and value, like
+// LATERAL VIEW OUTER does.
+type explodeColumn struct {
+	key, value                   *data.Field
+	keyConverter, valueConverter sqlutil.Converter
+	isMap                        bool
+}
+
+func newExplodeColumn(name string, typ *odpstype.Type, converters map[string]Converter, opts Options) (*explodeColumn, error) {
+	c := &explodeColumn{}
+	var valueType *odpstype.Type
+	switch typ.Name {
+	case odpstype.Array:
+		valueType = typ.Elem
+		c.keyConverter = converterFor(converters, &odpstype.Type{Name: "BIGINT"}, opts)
+		c.key = data.NewFieldFromFieldType(c.keyConverter.FrameConverter.FieldType, 0)
+		c.key.Name = name + ".index"
+	case odpstype.Map:
+		c.isMap = true
+		valueType = typ.Value
+		c.keyConverter = converterFor(converters, typ.Key, opts)
+		c.key = data.NewFieldFromFieldType(c.keyConverter.FrameConverter.FieldType, 0)
+		c.key.Name = name + ".key"
+	default:
+		return nil, fmt.Errorf("%w: %s is %s", ErrorNotExplodable, name, typ)
+	}
+	c.valueConverter = converterFor(converters, valueType, opts)
+	c.value = data.NewFieldFromFieldType(c.valueConverter.FrameConverter.FieldType, 0)
+	c.value.Name = name + ".value"
+	configureField(c.value, valueType, opts)
+	return c, nil
+}
+
+func (c *explodeColumn) fields() []*data.Field {
+	return []*data.Field{c.key, c.value}
+}
+
+// rows returns the number of rows the scanned value expands to.
+func (c *explodeColumn) rows(in interface{}) int {
+	return max(len(c.entries(in)), 1)
+}
+
+func (c *explodeColumn) append(in interface{}, o overflows) error {
+	entries := c.entries(in)
+	if len(entries) == 0 {
+		c.key.Extend(1)
+		c.value.Extend(1)
+		return nil
+	}
+
+	for _, e := range entries {
+		if err := appendData(c.key, c.keyConverter, e.key, o); err != nil {
+			return err
+		}
+		if err := appendData(c.value, c.valueConverter, e.value, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entries lists the elements of the scanned value, ordering map entries by key
+// so the rows do not depend on map iteration order.
+func (c *explodeColumn) entries(in interface{}) []entry {
+	switch v := in.(type) {
+	case *sqldriver.Array:
+		if v == nil || v.IsNull() {
+			return nil
+		}
+		values := (*data2.Array)(v).ToSlice()
+		entries := make([]entry, len(values))
+		for i, d := range values {
+			entries[i] = entry{key: data2.BigInt(i), value: d}
+		}
+		return entries
+	case *sqldriver.Map:
+		if v == nil || v.IsNull() {
+			return nil
+		}
+		m := (*data2.Map)(v).ToGoMap()
+		entries := make([]entry, 0, len(m))
+		for k, d := range m {
+			entries = append(entries, entry{key: k, value: d})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return lessKey(entries[i].key, entries[j].key)
+		})
+		return entries
+	}
+	return nil
+}
+
+// appendData converts a nested value with the converter of its declared type.
+func appendData(field *data.Field, converter sqlutil.Converter, d data2.Data, o overflows) error {
+	if isNull(d) {
+		field.Extend(1)
+		return nil
+	}
+	if err := o.appendValue(field, converter, scanValue(d)); err != nil {
+		return fmt.Errorf("%s: %w", field.Name, err)
+	}
+	return nil
+}
+
+// lessKey orders integer keys numerically and other keys by their text form.
+func lessKey(a, b data2.Data) bool {
+	x, errX := strconv.ParseInt(mapKey(a), 10, 64)
+	y, errY := strconv.ParseInt(mapKey(b), 10, 64)
+	if errX == nil && errY == nil {
+		return x < y
+	}
+	return mapKey(a) < mapKey(b)
+}
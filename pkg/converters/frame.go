@@ -0,0 +1,368 @@
+package converters
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/odpstype"
+	data2 "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/sqldriver"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+)
+
+// column produces the frame fields of a single result column.
+type column interface {
+	// fields returns the fields the column appends to.
+	fields() []*data.Field
+	// append converts the scanned value and appends it to the fields.
+	append(in interface{}, o overflows) error
+}
+
+// overflows counts the values of each field returned as null because they do
+// not fit the field type.
+type overflows map[*data.Field]int
+
+// appendValue converts a scanned value and appends it to the field. Values
+// that do not fit the field type, like DECIMALs out of the range of a scaled
+// int64, are appended as null and counted.
+func (o overflows) appendValue(field *data.Field, converter sqlutil.Converter, in interface{}) error {
+	v, err := converter.FrameConverter.ConverterFunc(in)
+	if errors.Is(err, ErrorDecimalOverflow) {
+		o[field]++
+		field.Extend(1)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	field.Append(v)
+	return nil
+}
+
+// notices returns a warning for each field of the frame with values that did not fit.
+func (o overflows) notices(frame *data.Frame) []data.Notice {
+	var notices []data.Notice
+	for _, f := range frame.Fields {
+		if n := o[f]; n > 0 {
+			notices = append(notices, data.Notice{
+				Severity: data.NoticeSeverityWarning,
+				Text:     fmt.Sprintf("Values of %s that do not fit in a scaled int64 are returned as null (%d rows). Use the string decimal mode to keep them.", f.Name, n),
+			})
+		}
+	}
+	return notices
+}
+
+// scalarColumn maps a column onto a single field with its converter.
+type scalarColumn struct {
+	field     *data.Field
+	converter sqlutil.Converter
+}
+
+func (c *scalarColumn) fields() []*data.Field {
+	return []*data.Field{c.field}
+}
+
+func (c *scalarColumn) append(in interface{}, o overflows) error {
+	return o.appendValue(c.field, c.converter, in)
+}
+
+// structLeaf is a non-STRUCT field nested in a flattened STRUCT column.
+type structLeaf struct {
+	path      []string
+	field     *data.Field
+	converter sqlutil.Converter
+}
+
+// flatStructColumn expands a STRUCT column into one field per nested field,
+// named after its path such as metrics.latency.p99.
+type flatStructColumn struct {
+	leaves []*structLeaf
+}
+
+func newFlatStructColumn(name string, typ *odpstype.Type, converters map[string]Converter, opts Options) *flatStructColumn {
+	c := &flatStructColumn{}
+	var walk func(prefix string, path []string, typ *odpstype.Type)
+	walk = func(prefix string, path []string, typ *odpstype.Type) {
+		for _, f := range typ.Fields {
+			name := prefix + "." + f.Name
+			fieldPath := append(append([]string{}, path...), f.Name)
+			if f.Type.Name == odpstype.Struct {
+				walk(name, fieldPath, f.Type)
+				continue
+			}
+			converter := converterFor(converters, f.Type, opts)
+			field := data.NewFieldFromFieldType(converter.FrameConverter.FieldType, 0)
+			field.Name = name
+			configureField(field, f.Type, opts)
+			c.leaves = append(c.leaves, &structLeaf{path: fieldPath, field: field, converter: converter})
+		}
+	}
+	walk(name, nil, typ)
+	return c
+}
+
+func (c *flatStructColumn) fields() []*data.Field {
+	fields := make([]*data.Field, len(c.leaves))
+	for i, l := range c.leaves {
+		fields[i] = l.field
+	}
+	return fields
+}
+
+func (c *flatStructColumn) append(in interface{}, o overflows) error {
+	var root *data2.Struct
+	if v, ok := in.(*sqldriver.Struct); ok && !v.IsNull() {
+		root = (*data2.Struct)(v)
+	}
+
+	for _, l := range c.leaves {
+		d := structField(root, l.path)
+		if isNull(d) {
+			l.field.Extend(1)
+			continue
+		}
+		if err := o.appendValue(l.field, l.converter, scanValue(d)); err != nil {
+			return fmt.Errorf("%s: %w", l.field.Name, err)
+		}
+	}
+	return nil
+}
+
+// structField follows path through nested structs, returning nil when any level is null.
+func structField(s *data2.Struct, path []string) data2.Data {
+	var d data2.Data
+	for _, name := range path {
+		if s == nil {
+			return nil
+		}
+		d = s.GetField(name)
+		switch v := d.(type) {
+		case *data2.Struct:
+			s = v
+		case data2.Struct:
+			s = &v
+		default:
+			s = nil
+		}
+	}
+	return d
+}
+
+func isNull(d data2.Data) bool {
+	switch d.(type) {
+	case nil, data2.NullData, *data2.NullData:
+		return true
+	}
+	return false
+}
+
+// scanValue wraps a nested value into the type the driver scans a top level
+// column of the same type into, so it can go through the same converter.
+func scanValue(d data2.Data) interface{} {
+	switch v := d.(type) {
+	case data2.Bool:
+		return &sqldriver.NullBool{Bool: bool(v), Valid: true}
+	case data2.TinyInt:
+		return &sqldriver.NullInt8{Int8: int8(v), Valid: true}
+	case data2.SmallInt:
+		return &sqldriver.NullInt16{Int16: int16(v), Valid: true}
+	case data2.Int:
+		return &sqldriver.NullInt32{Int32: int32(v), Valid: true}
+	case data2.BigInt:
+		return &sqldriver.NullInt64{Int64: int64(v), Valid: true}
+	case data2.Float:
+		return &sqldriver.NullFloat32{Float32: float32(v), Valid: true}
+	case data2.Double:
+		return &sqldriver.NullFloat64{Float64: float64(v), Valid: true}
+	case data2.String:
+		return &sqldriver.NullString{String: string(v), Valid: true}
+	case data2.Char:
+		return &sqldriver.NullString{String: v.Data(), Valid: true}
+	case data2.VarChar:
+		return &sqldriver.NullString{String: v.Data(), Valid: true}
+	case data2.Binary:
+		b := sqldriver.Binary(v)
+		return &b
+	case data2.Date:
+		return &sqldriver.NullDate{Time: time.Time(v), Valid: true}
+	case data2.DateTime:
+		return &sqldriver.NullDateTime{Time: time.Time(v), Valid: true}
+	case data2.Timestamp:
+		return &sqldriver.NullTimeStamp{Time: time.Time(v), Valid: true}
+	case *data2.Decimal:
+		return (*sqldriver.Decimal)(v)
+	case data2.Decimal:
+		return (*sqldriver.Decimal)(&v)
+	case *data2.Array:
+		return (*sqldriver.Array)(v)
+	case *data2.Map:
+		return (*sqldriver.Map)(v)
+	case *data2.Struct:
+		return (*sqldriver.Struct)(v)
+	case data2.IntervalDayTime:
+		return &nullIntervalDayTime{IntervalDayTime: v, Valid: true}
+	case data2.IntervalYearMonth:
+		return &nullIntervalYearMonth{IntervalYearMonth: v, Valid: true}
+	}
+	return d
+}
+
+// converterFor returns the converter of a parsed type, selected by its name,
+// falling back to the ODPS text form of the value. DECIMAL values are scaled
+// by the declared scale of the type.
+func converterFor(converters map[string]Converter, typ *odpstype.Type, opts Options) sqlutil.Converter {
+	if _, scale, ok := typ.DecimalParams(); ok {
+		return createConverter(odpstype.Decimal, decimalConverter(opts.DecimalMode, scale))
+	}
+	if c, ok := converters[typ.Name]; ok {
+		return createConverter(typ.Name, c)
+	}
+	return sqlutil.Converter{
+		Name: typ.Name,
+		FrameConverter: sqlutil.FrameConverter{
+			FieldType: data.FieldTypeNullableString,
+			ConverterFunc: func(in interface{}) (interface{}, error) {
+				if d, ok := in.(data2.Data); ok {
+					return makePtrToString(d.String()), nil
+				}
+				return makePtrToString(fmt.Sprint(in)), nil
+			},
+		},
+	}
+}
+
+// FrameFromRows reads the rows into a frame like sqlutil.FrameFromRows, using
+// the converters for the options and applying the per-column expansions they select.
+func FrameFromRows(rows *sql.Rows, opts Options) (*data.Frame, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	converters := convertersFor(opts)
+	parsed, list := columnConverters(types, converters, opts)
+	scanRow, err := sqlutil.MakeScanRow(types, names, list...)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]column, len(names))
+	overflowed := overflows{}
+	frame := data.NewFrame("")
+	explode := -1
+	for i, name := range names {
+		typ := parsed[i]
+		if opts.Explode != "" && strings.EqualFold(name, opts.Explode) {
+			if typ == nil {
+				return nil, fmt.Errorf("%w: %s", ErrorNotExplodable, name)
+			}
+			if columns[i], err = newExplodeColumn(name, typ, converters, opts); err != nil {
+				return nil, err
+			}
+			explode = i
+		} else {
+			columns[i] = newColumn(name, typ, scanRow.Converters[i], converters, opts)
+		}
+		frame.Fields = append(frame.Fields, columns[i].fields()...)
+
+		if typ == nil {
+			continue
+		}
+		if notice := configureField(frame.Fields[len(frame.Fields)-1], typ, opts); notice != nil {
+			frame.AppendNotices(*notice)
+		}
+	}
+	if opts.Explode != "" && explode < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrorUnknownExplodeColumn, opts.Explode)
+	}
+
+	for {
+		for rows.Next() {
+			r := scanRow.NewScannableRow()
+			if err := rows.Scan(r...); err != nil {
+				return nil, err
+			}
+
+			// the exploded column appends all its rows at once, the others
+			// repeat their value on each of them
+			count := 1
+			if explode >= 0 {
+				count = columns[explode].(*explodeColumn).rows(r[explode])
+			}
+			for i, c := range columns {
+				repeat := count
+				if i == explode {
+					repeat = 1
+				}
+				for j := 0; j < repeat; j++ {
+					if err := c.append(r[i], overflowed); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return frame, err
+	}
+
+	if notices := overflowed.notices(frame); len(notices) > 0 {
+		frame.AppendNotices(notices...)
+	}
+	return frame, nil
+}
+
+// columnConverters parses the declared type of each column and returns it
+// with a converter matching exactly that type string, so the converter of a
+// column depends only on its parsed type. Columns whose type does not parse,
+// or has no converter, are left to the default converter of sqlutil and have
+// a nil type.
+func columnConverters(types []*sql.ColumnType, converters map[string]Converter, opts Options) ([]*odpstype.Type, []sqlutil.Converter) {
+	parsed := make([]*odpstype.Type, len(types))
+	var list []sqlutil.Converter
+	for i, t := range types {
+		typ, err := odpstype.Parse(t.DatabaseTypeName())
+		if err != nil {
+			continue
+		}
+		parsed[i] = typ
+		if _, ok := converters[typ.Name]; !ok {
+			continue
+		}
+		converter := converterFor(converters, typ, opts)
+		converter.InputTypeName = t.DatabaseTypeName()
+		list = append(list, converter)
+	}
+	return parsed, list
+}
+
+// configureField sets the field config implied by the declared type, returning
+// a notice when the conversion may lose information.
+func configureField(field *data.Field, typ *odpstype.Type, opts Options) *data.Notice {
+	intervalField(field, typ, opts.IntervalMode)
+	return decimalField(field, typ, opts.DecimalMode)
+}
+
+func newColumn(name string, typ *odpstype.Type, converter sqlutil.Converter, converters map[string]Converter, opts Options) column {
+	if opts.FlattenStructs && typ != nil && typ.Name == odpstype.Struct {
+		return newFlatStructColumn(name, typ, converters, opts)
+	}
+
+	field := data.NewFieldFromFieldType(converter.FrameConverter.FieldType, 0)
+	field.Name = name
+	return &scalarColumn{field: field, converter: converter}
+}
@@ -0,0 +1,146 @@
+package converters
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/odpstest"
+	data2 "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/datatype"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlattenStructs(t *testing.T) {
+	latency := datatype.NewStructType(
+		datatype.NewStructFieldType("p50", datatype.DoubleType),
+		datatype.NewStructFieldType("p99", datatype.DoubleType),
+	)
+	metrics := datatype.NewStructType(
+		datatype.NewStructFieldType("host", datatype.StringType),
+		datatype.NewStructFieldType("count", datatype.BigIntType),
+		datatype.NewStructFieldType("latency", latency),
+		datatype.NewStructFieldType("tags", datatype.NewArrayType(datatype.StringType)),
+	)
+
+	newMetrics := func(host string, count int64, p50, p99 interface{}) *data2.Struct {
+		l := data2.NewStructWithTyp(latency)
+		require.NoError(t, l.SetField("p50", p50))
+		require.NoError(t, l.SetField("p99", p99))
+		tags := data2.NewArrayWithType(datatype.NewArrayType(datatype.StringType))
+		tags.UnSafeAppend(data2.String("a"))
+		s := data2.NewStructWithTyp(metrics)
+		require.NoError(t, s.SetField("host", data2.String(host)))
+		require.NoError(t, s.SetField("count", data2.BigInt(count)))
+		require.NoError(t, s.SetField("latency", l))
+		require.NoError(t, s.SetField("tags", tags))
+		return s
+	}
+
+	result := odpstest.Result{
+		Columns: []odpstest.Column{{Name: "id", Type: "BIGINT"}, {Name: "metrics", Type: metrics.Name()}},
+		Rows: [][]driver.Value{
+			{int64(1), newMetrics("a", 10, data2.Double(1.5), data2.Double(9.5))},
+			{int64(2), newMetrics("b", 20, data2.Double(2.5), data2.Null)},
+			{int64(3), nil},
+		},
+	}
+
+	frame, err := FrameFromRows(odpstest.Query(t, result), Options{FlattenStructs: true})
+	require.NoError(t, err)
+
+	names := make([]string, len(frame.Fields))
+	for i, f := range frame.Fields {
+		names[i] = f.Name
+	}
+	require.Equal(t, []string{"id", "metrics.host", "metrics.count", "metrics.latency.p50", "metrics.latency.p99", "metrics.tags"}, names)
+
+	require.Equal(t, data.FieldTypeNullableString, frame.Fields[1].Type())
+	require.Equal(t, data.FieldTypeNullableInt64, frame.Fields[2].Type())
+	require.Equal(t, data.FieldTypeNullableFloat64, frame.Fields[4].Type())
+	require.Equal(t, data.FieldTypeNullableJSON, frame.Fields[5].Type())
+
+	require.Equal(t, "a", *frame.Fields[1].At(0).(*string))
+	require.Equal(t, int64(20), *frame.Fields[2].At(1).(*int64))
+	require.Equal(t, 9.5, *frame.Fields[4].At(0).(*float64))
+	require.Nil(t, frame.Fields[4].At(1))
+	require.Equal(t, json.RawMessage(`["a"]`), *frame.Fields[5].At(0).(*json.RawMessage))
+
+	for _, f := range frame.Fields[1:] {
+		v, ok := f.ConcreteAt(2)
+		require.False(t, ok, "%s of a null struct is null, got %v", f.Name, v)
+	}
+
+	frame, err = FrameFromRows(odpstest.Query(t, result), Options{})
+	require.NoError(t, err)
+	require.Len(t, frame.Fields, 2)
+	require.Equal(t, data.FieldTypeNullableJSON, frame.Fields[1].Type())
+}
+
+func TestExplode(t *testing.T) {
+	samplesType := datatype.NewArrayType(datatype.DoubleType)
+	tagsType := datatype.NewMapType(datatype.StringType, datatype.BigIntType)
+
+	samples := func(values ...data2.Data) *data2.Array {
+		a := data2.NewArrayWithType(samplesType)
+		a.UnSafeAppend(values...)
+		return a
+	}
+	tags := func(kv map[string]int64) *data2.Map {
+		m := data2.NewMapWithType(tagsType)
+		for k, v := range kv {
+			require.NoError(t, m.Set(data2.String(k), data2.BigInt(v)))
+		}
+		return m
+	}
+
+	result := odpstest.Result{
+		Columns: []odpstest.Column{{Name: "id", Type: "BIGINT"}, {Name: "samples", Type: samplesType.Name()}, {Name: "tags", Type: tagsType.Name()}},
+		Rows: [][]driver.Value{
+			{int64(1), samples(data2.Double(1.5), data2.Null, data2.Double(3)), tags(map[string]int64{"b": 2, "a": 1})},
+			{int64(2), nil, tags(nil)},
+		},
+	}
+
+	fieldValues := func(f *data.Field) []interface{} {
+		values := make([]interface{}, f.Len())
+		for i := range values {
+			if v, ok := f.ConcreteAt(i); ok {
+				values[i] = v
+			}
+		}
+		return values
+	}
+
+	t.Run("array", func(t *testing.T) {
+		frame, err := FrameFromRows(odpstest.Query(t, result), Options{Explode: "samples"})
+		require.NoError(t, err)
+		require.Equal(t, "samples.index", frame.Fields[1].Name)
+		require.Equal(t, "samples.value", frame.Fields[2].Name)
+		require.Equal(t, data.FieldTypeNullableFloat64, frame.Fields[2].Type())
+		require.Equal(t, []interface{}{int64(1), int64(1), int64(1), int64(2)}, fieldValues(frame.Fields[0]))
+		require.Equal(t, []interface{}{int64(0), int64(1), int64(2), nil}, fieldValues(frame.Fields[1]))
+		require.Equal(t, []interface{}{1.5, nil, 3.0, nil}, fieldValues(frame.Fields[2]))
+		require.Equal(t, 4, frame.Fields[3].Len())
+	})
+
+	t.Run("map", func(t *testing.T) {
+		// the column is matched ignoring case, like MaxCompute identifiers
+		frame, err := FrameFromRows(odpstest.Query(t, result), Options{Explode: "TAGS"})
+		require.NoError(t, err)
+		require.Equal(t, "tags.key", frame.Fields[2].Name)
+		require.Equal(t, data.FieldTypeNullableString, frame.Fields[2].Type())
+		require.Equal(t, data.FieldTypeNullableInt64, frame.Fields[3].Type())
+		require.Equal(t, []interface{}{int64(1), int64(1), int64(2)}, fieldValues(frame.Fields[0]))
+		require.Equal(t, []interface{}{"a", "b", nil}, fieldValues(frame.Fields[2]))
+		require.Equal(t, []interface{}{int64(1), int64(2), nil}, fieldValues(frame.Fields[3]))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := FrameFromRows(odpstest.Query(t, result), Options{Explode: "missing"})
+		require.ErrorIs(t, err, ErrorUnknownExplodeColumn)
+		_, err = FrameFromRows(odpstest.Query(t, result), Options{Explode: "id"})
+		require.ErrorIs(t, err, ErrorNotExplodable)
+	})
+}
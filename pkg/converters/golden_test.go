@@ -0,0 +1,109 @@
+package converters
+
+import (
+	"database/sql/driver"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/odpstest"
+	data2 "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/datatype"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana-plugin-sdk-go/experimental"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// allTypes has a column of every MaxCompute type, with a row of values and a
+// row of NULLs, as the ODPS driver returns them.
+func allTypes(t *testing.T) odpstest.Result {
+	arrayType := datatype.NewArrayType(datatype.BigIntType)
+	array := data2.NewArrayWithType(arrayType)
+	array.UnSafeAppend(data2.BigInt(1), nil)
+
+	mapType := datatype.NewMapType(datatype.StringType, datatype.BigIntType)
+	m := data2.NewMapWithType(mapType)
+	require.NoError(t, m.Set(data2.String("a"), data2.BigInt(1)))
+
+	structType := datatype.NewStructType(datatype.NewStructFieldType("a", datatype.BigIntType))
+	s := data2.NewStructWithTyp(structType)
+	require.NoError(t, s.SetField("a", data2.BigInt(1)))
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	columns := []odpstest.Column{
+		{Name: "tinyint", Type: "TINYINT"},
+		{Name: "smallint", Type: "SMALLINT"},
+		{Name: "int", Type: "INT"},
+		{Name: "bigint", Type: "BIGINT"},
+		{Name: "float", Type: "FLOAT"},
+		{Name: "double", Type: "DOUBLE"},
+		{Name: "decimal", Type: "DECIMAL(10,2)"},
+		{Name: "string", Type: "STRING"},
+		{Name: "varchar", Type: "VARCHAR(4)"},
+		{Name: "char", Type: "CHAR(4)"},
+		{Name: "binary", Type: "BINARY"},
+		{Name: "boolean", Type: "BOOLEAN"},
+		{Name: "date", Type: "DATE"},
+		{Name: "datetime", Type: "DATETIME"},
+		{Name: "timestamp", Type: "TIMESTAMP"},
+		{Name: "array", Type: arrayType.Name()},
+		{Name: "map", Type: mapType.Name()},
+		{Name: "struct", Type: structType.Name()},
+		{Name: "interval_day_time", Type: "INTERVAL_DAY_TIME"},
+		{Name: "interval_year_month", Type: "INTERVAL_YEAR_MONTH"},
+		{Name: "void", Type: "VOID"},
+		{Name: "json", Type: "JSON"},
+		{Name: "timestamp_ntz", Type: "TIMESTAMP_NTZ"},
+	}
+	values := []driver.Value{
+		int8(1), int16(2), int(3), int64(4), float32(1.5), float64(2.5),
+		data2.NewDecimal(10, 2, "3.14"), "string", "vchr", "char", []byte("hi"), true,
+		day, day.Add(time.Hour), day.Add(time.Hour + 123456789),
+		array, m, s,
+		data2.NewIntervalDayTime(90061, 0), data2.IntervalYearMonth(14), nil,
+		&data2.Json{Data: `{"a":[1,null]}`, Valid: true}, day.Add(time.Hour + 500*time.Millisecond),
+	}
+	return odpstest.Result{
+		Columns: columns,
+		Rows:    [][]driver.Value{values, make([]driver.Value, len(columns))},
+	}
+}
+
+func shanghai(t *testing.T) *time.Location {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	return loc
+}
+
+func TestNullGolden(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts Options
+	}{
+		{name: "types", opts: Options{}},
+		{name: "types-nested-string", opts: Options{NestedAsString: true}},
+		{name: "types-decimal-float", opts: Options{DecimalMode: DecimalFloat}},
+		{name: "types-decimal-scaled", opts: Options{DecimalMode: DecimalScaled}},
+		{name: "types-interval-numeric", opts: Options{IntervalMode: IntervalNumeric}},
+		{name: "types-binary-hex", opts: Options{BinaryMode: BinaryHex}},
+		{name: "types-binary-length", opts: Options{BinaryMode: BinaryLength}},
+		{name: "types-ntz-shanghai", opts: Options{NTZLocation: shanghai(t)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			frame, err := FrameFromRows(odpstest.Query(t, allTypes(t)), tc.opts)
+			require.NoError(t, err)
+
+			for _, f := range frame.Fields {
+				require.True(t, f.Nullable(), "%s is not nullable", f.Name)
+				_, ok := f.ConcreteAt(1)
+				require.False(t, ok, "%s is not null in the NULL row", f.Name)
+			}
+
+			res := backend.DataResponse{Frames: data.Frames{frame}}
+			experimental.CheckGoldenJSONResponse(t, "testdata", tc.name, &res, *update)
+		})
+	}
+}
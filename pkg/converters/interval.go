@@ -0,0 +1,146 @@
+package converters
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/odpstype"
+	data2 "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// IntervalMode selects how INTERVAL values are returned.
+type IntervalMode string
+
+const (
+	// IntervalString renders intervals as text such as "1 days, 3661000 ms".
+	IntervalString IntervalMode = "string"
+	// IntervalNumeric returns day-time intervals in milliseconds and year-month
+	// intervals in months, with units so panels render them as durations.
+	IntervalNumeric IntervalMode = "numeric"
+)
+
+// Units of the numeric interval fields.
+const (
+	unitDurationMs = "dtdurationms"
+	unitMonths     = "suffix: months"
+)
+
+var ErrorInvalidIntervalMode = errors.New("invalid interval mode. Expected string or numeric")
+
+// ValidateIntervalMode accepts the known modes and the empty default.
+func ValidateIntervalMode(mode IntervalMode) error {
+	switch mode {
+	case "", IntervalString, IntervalNumeric:
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrorInvalidIntervalMode, mode)
+}
+
+// nullIntervalDayTime scans an INTERVAL_DAY_TIME, recording the NULLs its
+// Scan method ignores.
+type nullIntervalDayTime struct {
+	data2.IntervalDayTime
+	Valid bool
+}
+
+func (n *nullIntervalDayTime) Scan(value interface{}) error {
+	n.Valid = value != nil
+	return n.IntervalDayTime.Scan(value)
+}
+
+// nullIntervalYearMonth scans an INTERVAL_YEAR_MONTH, recording the NULLs its
+// Scan method ignores.
+type nullIntervalYearMonth struct {
+	data2.IntervalYearMonth
+	Valid bool
+}
+
+func (n *nullIntervalYearMonth) Scan(value interface{}) error {
+	n.Valid = value != nil
+	return n.IntervalYearMonth.Scan(value)
+}
+
+// intervalMilliseconds returns the length of a day-time interval in milliseconds.
+func intervalMilliseconds(i data2.IntervalDayTime) int64 {
+	return i.Seconds()*1000 + int64(i.NanosFraction())/int64(time.Millisecond)
+}
+
+func intervalDayTimeConverter(mode IntervalMode) Converter {
+	converter := Converter{
+		fieldType: data.FieldTypeNullableString,
+		scanType:  reflect.TypeOf(nullIntervalDayTime{}),
+	}
+	if mode == IntervalNumeric {
+		converter.fieldType = data.FieldTypeNullableInt64
+	}
+
+	converter.convert = func(in interface{}) (interface{}, error) {
+		if in == nil {
+			in = &nullIntervalDayTime{}
+		}
+		v, ok := in.(*nullIntervalDayTime)
+		if !ok {
+			return nil, invalidType("INTERVAL_DAY_TIME")
+		}
+		if mode == IntervalNumeric {
+			if !v.Valid {
+				return (*int64)(nil), nil
+			}
+			ms := intervalMilliseconds(v.IntervalDayTime)
+			return &ms, nil
+		}
+		if !v.Valid {
+			return (*string)(nil), nil
+		}
+		return makePtrToString(v.IntervalDayTime.String()), nil
+	}
+	return converter
+}
+
+func intervalYearMonthConverter(mode IntervalMode) Converter {
+	converter := Converter{
+		fieldType: data.FieldTypeNullableString,
+		scanType:  reflect.TypeOf(nullIntervalYearMonth{}),
+	}
+	if mode == IntervalNumeric {
+		converter.fieldType = data.FieldTypeNullableInt32
+	}
+
+	converter.convert = func(in interface{}) (interface{}, error) {
+		if in == nil {
+			in = &nullIntervalYearMonth{}
+		}
+		v, ok := in.(*nullIntervalYearMonth)
+		if !ok {
+			return nil, invalidType("INTERVAL_YEAR_MONTH")
+		}
+		if mode == IntervalNumeric {
+			if !v.Valid {
+				return (*int32)(nil), nil
+			}
+			months := int32(v.IntervalYearMonth)
+			return &months, nil
+		}
+		if !v.Valid {
+			return (*string)(nil), nil
+		}
+		return makePtrToString(v.IntervalYearMonth.String()), nil
+	}
+	return converter
+}
+
+// intervalField sets the unit of a numeric INTERVAL field.
+func intervalField(field *data.Field, typ *odpstype.Type, mode IntervalMode) {
+	if mode != IntervalNumeric {
+		return
+	}
+	switch typ.Name {
+	case "INTERVAL_DAY_TIME":
+		field.SetConfig(&data.FieldConfig{Unit: unitDurationMs})
+	case "INTERVAL_YEAR_MONTH":
+		field.SetConfig(&data.FieldConfig{Unit: unitMonths})
+	}
+}
@@ -0,0 +1,68 @@
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	odpsdata "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// nullJSON scans a JSON column, which the driver passes as a *data.Json
+// holding the text of the document.
+type nullJSON struct {
+	Raw   json.RawMessage
+	Valid bool
+}
+
+func (n *nullJSON) Scan(value interface{}) error {
+	var text []byte
+	switch v := value.(type) {
+	case nil:
+		n.Raw, n.Valid = nil, false
+		return nil
+	case *odpsdata.Json:
+		if v == nil || !v.Valid {
+			n.Raw, n.Valid = nil, false
+			return nil
+		}
+		text = []byte(v.Data)
+	case string:
+		text = []byte(v)
+	case []byte:
+		text = append([]byte{}, v...)
+	default:
+		return fmt.Errorf("cannot convert %T to JSON", value)
+	}
+
+	// a value that is not a JSON document is kept as a JSON string
+	if !json.Valid(text) {
+		b, err := json.Marshal(string(text))
+		if err != nil {
+			return err
+		}
+		text = b
+	}
+	n.Raw, n.Valid = text, true
+	return nil
+}
+
+var jsonTypeConverter = Converter{
+	fieldType: data.FieldTypeNullableJSON,
+	scanType:  reflect.TypeOf(nullJSON{}),
+	convert: func(in interface{}) (interface{}, error) {
+		if in == nil {
+			return (*json.RawMessage)(nil), nil
+		}
+		v, ok := in.(*nullJSON)
+		if !ok {
+			return nil, invalidType("JSON")
+		}
+		if !v.Valid {
+			return (*json.RawMessage)(nil), nil
+		}
+		raw := v.Raw
+		return &raw, nil
+	},
+}
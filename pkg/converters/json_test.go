@@ -0,0 +1,41 @@
+package converters
+
+import (
+	"encoding/json"
+	"testing"
+
+	odpsdata "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullJSONScan(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "driver value", value: &odpsdata.Json{Data: `{"a":1}`, Valid: true}, want: `{"a":1}`},
+		{name: "object", value: `{"a":1}`, want: `{"a":1}`},
+		{name: "bytes", value: []byte(`[1,2]`), want: `[1,2]`},
+		{name: "number", value: "3.5", want: `3.5`},
+		{name: "not a document", value: "plain text", want: `"plain text"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var n nullJSON
+			require.NoError(t, n.Scan(tc.value))
+			require.True(t, n.Valid)
+
+			v, err := jsonTypeConverter.convert(&n)
+			require.NoError(t, err)
+			require.Equal(t, json.RawMessage(tc.want), *v.(*json.RawMessage))
+		})
+	}
+
+	for _, value := range []interface{}{nil, &odpsdata.Json{}} {
+		var n nullJSON
+		require.NoError(t, n.Scan(value))
+		v, err := jsonTypeConverter.convert(&n)
+		require.NoError(t, err)
+		require.Nil(t, v.(*json.RawMessage))
+	}
+}
@@ -0,0 +1,281 @@
+package converters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	data2 "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/sqldriver"
+)
+
+// member is a single STRUCT field, kept in declaration order when rendered as JSON.
+type member struct {
+	name  string
+	value interface{}
+}
+
+// object renders STRUCT values as JSON objects without reordering their fields.
+type object []member
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(m.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// nestedValue converts a value held by an ARRAY, MAP or STRUCT into one that
+// encoding/json renders faithfully. Decimals stay exact JSON numbers, dates
+// and times use ISO 8601 and binary is base64 encoded.
+func nestedValue(d data2.Data) (interface{}, error) {
+	switch v := d.(type) {
+	case nil, data2.NullData, *data2.NullData:
+		return nil, nil
+	case data2.Bool:
+		return bool(v), nil
+	case data2.TinyInt:
+		return int64(v), nil
+	case data2.SmallInt:
+		return int64(v), nil
+	case data2.Int:
+		return int64(v), nil
+	case data2.BigInt:
+		return int64(v), nil
+	case data2.Float:
+		return jsonFloat(float64(v)), nil
+	case data2.Double:
+		return jsonFloat(float64(v)), nil
+	case data2.String:
+		return string(v), nil
+	case data2.Char:
+		return v.Data(), nil
+	case *data2.Char:
+		return v.Data(), nil
+	case data2.VarChar:
+		return v.Data(), nil
+	case *data2.VarChar:
+		return v.Data(), nil
+	case data2.Binary:
+		return []byte(v), nil
+	case data2.Date:
+		return time.Time(v).Format(time.DateOnly), nil
+	case data2.DateTime:
+		return time.Time(v).Format(time.RFC3339), nil
+	case data2.Timestamp:
+		return time.Time(v).Format(time.RFC3339Nano), nil
+	case data2.Decimal:
+		return decimalNumber(&v), nil
+	case *data2.Decimal:
+		return decimalNumber(v), nil
+	case data2.IntervalDayTime, data2.IntervalYearMonth:
+		return v.String(), nil
+	case data2.Array:
+		return nestedArray(&v)
+	case *data2.Array:
+		return nestedArray(v)
+	case data2.Map:
+		return nestedMap(&v)
+	case *data2.Map:
+		return nestedMap(v)
+	case data2.Struct:
+		return nestedStruct(&v)
+	case *data2.Struct:
+		return nestedStruct(v)
+	}
+	return nil, fmt.Errorf("unsupported nested type %T", d)
+}
+
+func nestedArray(a *data2.Array) (interface{}, error) {
+	if a == nil {
+		return nil, nil
+	}
+	res := make([]interface{}, 0, a.Len())
+	for _, d := range a.ToSlice() {
+		v, err := nestedValue(d)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// nestedMap renders a MAP as a JSON object. JSON keys are strings, so other
+// key types use their MaxCompute text form.
+func nestedMap(m *data2.Map) (interface{}, error) {
+	if m == nil {
+		return nil, nil
+	}
+	res := make(map[string]interface{}, len(m.ToGoMap()))
+	for k, d := range m.ToGoMap() {
+		v, err := nestedValue(d)
+		if err != nil {
+			return nil, err
+		}
+		res[mapKey(k)] = v
+	}
+	return res, nil
+}
+
+func nestedStruct(s *data2.Struct) (interface{}, error) {
+	if s == nil {
+		return nil, nil
+	}
+	res := make(object, 0, len(s.Fields()))
+	for _, f := range s.Fields() {
+		v, err := nestedValue(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, member{name: f.Name, value: v})
+	}
+	return res, nil
+}
+
+func mapKey(k data2.Data) string {
+	switch v := k.(type) {
+	case nil, data2.NullData:
+		return "null"
+	case data2.String:
+		return string(v)
+	case data2.Char:
+		return v.Data()
+	case data2.VarChar:
+		return v.Data()
+	}
+	return k.String()
+}
+
+// decimalNumber keeps every digit of a decimal, which float64 would round.
+func decimalNumber(d *data2.Decimal) interface{} {
+	if d.Value() == "" {
+		return nil
+	}
+	n := json.Number(d.Value())
+	if _, err := json.Marshal(n); err != nil {
+		return d.Value()
+	}
+	return n
+}
+
+// jsonFloat renders NaN and infinities, which JSON numbers cannot hold, as strings.
+func jsonFloat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprint(f)
+	}
+	return f
+}
+
+// nestedJSON converts a scanned ARRAY, MAP or STRUCT column into JSON.
+func nestedJSON(name string) func(in interface{}) (interface{}, error) {
+	return func(in interface{}) (interface{}, error) {
+		var (
+			v   interface{}
+			err error
+		)
+		switch d := in.(type) {
+		case nil:
+			return (*json.RawMessage)(nil), nil
+		case *sqldriver.Array:
+			if d.IsNull() {
+				return (*json.RawMessage)(nil), nil
+			}
+			v, err = nestedArray((*data2.Array)(d))
+		case *sqldriver.Map:
+			if d.IsNull() {
+				return (*json.RawMessage)(nil), nil
+			}
+			v, err = nestedMap((*data2.Map)(d))
+		case *sqldriver.Struct:
+			if d.IsNull() {
+				return (*json.RawMessage)(nil), nil
+			}
+			v, err = nestedStruct((*data2.Struct)(d))
+		default:
+			return nil, invalidType(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		msg := json.RawMessage(raw)
+		return &msg, nil
+	}
+}
+
+// literal renders a value in the ODPS literal form of its String method,
+// which panics on the nil elements the tunnel returns for NULLs. Map entries
+// are sorted by key so the rendering is stable.
+func literal(d data2.Data) string {
+	switch v := d.(type) {
+	case nil, data2.NullData:
+		return "NULL"
+	case data2.Array:
+		return literal(&v)
+	case *data2.Array:
+		elems := make([]string, 0, v.Len())
+		for _, e := range v.ToSlice() {
+			elems = append(elems, literal(e))
+		}
+		return "array(" + strings.Join(elems, ", ") + ")"
+	case data2.Map:
+		return literal(&v)
+	case *data2.Map:
+		m := v.ToGoMap()
+		keys := make([]data2.Data, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return lessKey(keys[i], keys[j]) })
+		entries := make([]string, len(keys))
+		for i, k := range keys {
+			entries[i] = sqlLiteral(k) + ", " + sqlLiteral(m[k])
+		}
+		return "map(" + strings.Join(entries, ", ") + ")"
+	case data2.Struct:
+		return literal(&v)
+	case *data2.Struct:
+		fields := make([]string, 0, len(v.Fields()))
+		for _, f := range v.Fields() {
+			fields = append(fields, f.Name+":"+literal(f.Value))
+		}
+		return "struct<" + strings.Join(fields, ",") + ">"
+	}
+	return d.String()
+}
+
+// sqlLiteral renders a scalar as in SQL, such as 'v' for a string.
+func sqlLiteral(d data2.Data) string {
+	switch d.(type) {
+	case nil, data2.NullData:
+		return "NULL"
+	case data2.Array, *data2.Array, data2.Map, *data2.Map, data2.Struct, *data2.Struct:
+		return literal(d)
+	}
+	return d.Sql()
+}
@@ -0,0 +1,66 @@
+package converters
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// timestampNTZLayout is the text form of a TIMESTAMP_NTZ value.
+const timestampNTZLayout = "2006-01-02 15:04:05.999999999"
+
+// nullTimestampNTZ scans a TIMESTAMP_NTZ column. Its value is a wall clock
+// without timezone, which the driver passes as a time in UTC.
+type nullTimestampNTZ struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (n *nullTimestampNTZ) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		n.Time, n.Valid = time.Time{}, false
+		return nil
+	case time.Time:
+		n.Time = v
+	case string:
+		t, err := time.ParseInLocation(timestampNTZLayout, v, time.UTC)
+		if err != nil {
+			return err
+		}
+		n.Time = t
+	case []byte:
+		return n.Scan(string(v))
+	default:
+		return fmt.Errorf("cannot convert %T to TIMESTAMP_NTZ", value)
+	}
+	n.Valid = true
+	return nil
+}
+
+// timestampNTZConverter places TIMESTAMP_NTZ wall clocks in loc, UTC when nil.
+func timestampNTZConverter(loc *time.Location) Converter {
+	return Converter{
+		fieldType: data.FieldTypeNullableTime,
+		scanType:  reflect.TypeOf(nullTimestampNTZ{}),
+		convert: func(in interface{}) (interface{}, error) {
+			if in == nil {
+				return (*time.Time)(nil), nil
+			}
+			v, ok := in.(*nullTimestampNTZ)
+			if !ok {
+				return nil, invalidType("TIMESTAMP_NTZ")
+			}
+			if !v.Valid {
+				return (*time.Time)(nil), nil
+			}
+			t := v.Time.UTC()
+			if loc != nil {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc).UTC()
+			}
+			return &t, nil
+		},
+	}
+}
@@ -0,0 +1,30 @@
+package converters
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/odpstest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeConverters(t *testing.T) {
+	// the SDK decodes DATETIME and TIMESTAMP values from their epoch with
+	// time.Unix, so they are instants in the local zone of the plugin
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	datetime := time.Date(2017, time.November, 11, 0, 0, 1, 0, time.UTC)
+	timestamp := time.Date(2017, time.November, 11, 0, 0, 2, 123456789, time.UTC)
+	result := odpstest.Result{
+		Columns: []odpstest.Column{{Name: "datetime", Type: "DATETIME"}, {Name: "timestamp", Type: "TIMESTAMP"}},
+		Rows:    [][]driver.Value{{datetime.In(shanghai), timestamp.In(shanghai)}, {nil, nil}},
+	}
+
+	frame, err := FrameFromRows(odpstest.Query(t, result), Options{})
+	require.NoError(t, err)
+	require.Equal(t, datetime, *frame.Fields[0].At(0).(*time.Time))
+	require.Equal(t, timestamp, *frame.Fields[1].At(0).(*time.Time))
+	require.Nil(t, frame.Fields[0].At(1))
+	require.Nil(t, frame.Fields[1].At(1))
+}
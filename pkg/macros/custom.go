@@ -0,0 +1,120 @@
+package macros
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/sqlds/v3"
+)
+
+var (
+	ErrorInvalidCustomMacro = errors.New("invalid custom macro")
+
+	macroNamePattern = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+	paramPattern     = regexp.MustCompile(`\$\{(\w+)\}`)
+)
+
+// CustomMacro is an admin defined macro configured in the datasource settings.
+// Its body references the parameters as ${param} and may call the built-in
+// macros, for example:
+//
+//	{"name": "regionFilter", "params": ["column"], "body": "${column} IN ('id', 'sg')"}
+type CustomMacro struct {
+	Name        string   `json:"name"`
+	Params      []string `json:"params"`
+	Body        string   `json:"body"`
+	Description string   `json:"description,omitempty"`
+}
+
+// Validate checks the name, the parameters and that the body only references
+// declared parameters.
+func (c CustomMacro) Validate() error {
+	if !macroNamePattern.MatchString(c.Name) {
+		return fmt.Errorf("%w: name %q must be a valid identifier", ErrorInvalidCustomMacro, c.Name)
+	}
+	for _, m := range Registry {
+		if m.Name == c.Name {
+			return fmt.Errorf("%w: $__%s is a built-in macro", ErrorInvalidCustomMacro, c.Name)
+		}
+	}
+	if strings.TrimSpace(c.Body) == "" {
+		return fmt.Errorf("%w: $__%s has an empty body", ErrorInvalidCustomMacro, c.Name)
+	}
+
+	params := map[string]bool{}
+	for _, p := range c.Params {
+		if !macroNamePattern.MatchString(p) {
+			return fmt.Errorf("%w: $__%s parameter %q must be a valid identifier", ErrorInvalidCustomMacro, c.Name, p)
+		}
+		if params[p] {
+			return fmt.Errorf("%w: $__%s declares parameter %q twice", ErrorInvalidCustomMacro, c.Name, p)
+		}
+		params[p] = true
+	}
+	for _, m := range paramPattern.FindAllStringSubmatch(c.Body, -1) {
+		if !params[m[1]] {
+			return fmt.Errorf("%w: $__%s references undeclared parameter %q", ErrorInvalidCustomMacro, c.Name, m[1])
+		}
+	}
+
+	return nil
+}
+
+// ValidateCustomMacros validates every macro and rejects duplicate names.
+func ValidateCustomMacros(custom []CustomMacro) error {
+	seen := map[string]bool{}
+	for _, c := range custom {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("%w: $__%s is defined twice", ErrorInvalidCustomMacro, c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return nil
+}
+
+// macro describes the custom macro in the registry format.
+func (c CustomMacro) macro() Macro {
+	args := make([]Argument, len(c.Params))
+	for i, p := range c.Params {
+		args[i] = Argument{Name: p, Description: fmt.Sprintf("Replaces ${%s} in the body", p)}
+	}
+	description := c.Description
+	if description == "" {
+		description = "Custom macro: " + c.Body
+	}
+	example := "$__" + c.Name
+	if len(c.Params) > 0 {
+		example += "(" + strings.Join(c.Params, ", ") + ")"
+	}
+
+	return Macro{
+		Name:        c.Name,
+		Description: description,
+		MinArgs:     len(c.Params),
+		MaxArgs:     len(c.Params),
+		Args:        args,
+		Example:     example,
+		apply:       c.expand,
+	}
+}
+
+// expand substitutes the arguments into the body and expands the built-in
+// macros it calls. Custom macros are not expanded inside a body, which rules
+// out recursion.
+func (c CustomMacro) expand(o Options, query *sqlds.Query, args []string) (string, error) {
+	values := map[string]string{}
+	for i, p := range c.Params {
+		values[p] = args[i]
+	}
+	body := paramPattern.ReplaceAllStringFunc(c.Body, func(ref string) string {
+		return values[paramPattern.FindStringSubmatch(ref)[1]]
+	})
+
+	o.Custom = nil
+	return Interpolate(query.WithSQL(body), o.Macros())
+}
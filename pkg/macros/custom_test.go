@@ -0,0 +1,77 @@
+package macros_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/sqlds/v3"
+	"github.com/stretchr/testify/require"
+	"gotest.tools/assert"
+)
+
+func TestCustomMacros(t *testing.T) {
+	from, _ := time.Parse("2006-01-02T15:04:05.000Z", "2014-11-12T11:45:26.371Z")
+	to, _ := time.Parse("2006-01-02T15:04:05.000Z", "2015-11-12T11:45:26.371Z")
+	opts := macros.Options{Custom: []macros.CustomMacro{
+		{Name: "regionFilter", Params: []string{"column"}, Body: "${column} IN ('id', 'sg')"},
+		{Name: "recent", Params: []string{"column", "type"}, Body: "$__timeFilter(${column}, ${type}) AND ${column} IS NOT NULL"},
+		{Name: "lastPartition", Body: "ds = to_char(dateadd($__timeTo(), -1, 'dd'), 'yyyymmdd')"},
+	}}
+	type test struct {
+		name   string
+		input  string
+		output string
+	}
+	tests := []test{
+		{input: "select * from foo where $__regionFilter(region)", output: "select * from foo where region IN ('id', 'sg')", name: "substitutes parameters"},
+		{input: "select * from foo where $__recent(t, DATETIME)", output: "select * from foo where t >= DATETIME'2014-11-12 11:45:26' AND t <= DATETIME'2015-11-12 11:45:26' AND t IS NOT NULL", name: "expands built-in macros in the body"},
+		{input: "select * from foo where $__lastPartition", output: "select * from foo where ds = to_char(dateadd('2015-11-12 11:45:26', -1, 'dd'), 'yyyymmdd')", name: "macro without parameters"},
+	}
+	fns := opts.Macros()
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("[%d/%d] %s", i+1, len(tests), tc.name), func(t *testing.T) {
+			query := &sqlds.Query{RawSQL: tc.input, TimeRange: backend.TimeRange{From: from, To: to}}
+			res, err := macros.Interpolate(query, fns)
+			require.NoError(t, err)
+			assert.Equal(t, tc.output, res)
+		})
+	}
+
+	_, err := macros.Interpolate(&sqlds.Query{RawSQL: "select $__regionFilter() from foo"}, fns)
+	require.ErrorContains(t, err, "$__regionFilter expected 1 argument, received 0")
+
+	registry := opts.Registry()
+	require.Equal(t, len(macros.Registry)+3, len(registry))
+	require.Equal(t, "$__recent(column, type)", registry[len(registry)-2].Example)
+}
+
+func TestValidateCustomMacros(t *testing.T) {
+	tests := []struct {
+		description string
+		macros      []macros.CustomMacro
+		wantErr     bool
+	}{
+		{description: "valid macro", macros: []macros.CustomMacro{{Name: "region", Params: []string{"col"}, Body: "${col} = 'id'"}}},
+		{description: "invalid name", macros: []macros.CustomMacro{{Name: "my-macro", Body: "1"}}, wantErr: true},
+		{description: "built-in name", macros: []macros.CustomMacro{{Name: "timeFilter", Params: []string{"col"}, Body: "${col}"}}, wantErr: true},
+		{description: "empty body", macros: []macros.CustomMacro{{Name: "empty", Body: " "}}, wantErr: true},
+		{description: "invalid parameter", macros: []macros.CustomMacro{{Name: "p", Params: []string{"a b"}, Body: "1"}}, wantErr: true},
+		{description: "duplicate parameter", macros: []macros.CustomMacro{{Name: "p", Params: []string{"a", "a"}, Body: "${a}"}}, wantErr: true},
+		{description: "undeclared parameter", macros: []macros.CustomMacro{{Name: "p", Params: []string{"a"}, Body: "${b}"}}, wantErr: true},
+		{description: "duplicate macro", macros: []macros.CustomMacro{{Name: "p", Body: "1"}, {Name: "p", Body: "2"}}, wantErr: true},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("[%d/%d] %s", i+1, len(tests), tc.description), func(t *testing.T) {
+			err := macros.ValidateCustomMacros(tc.macros)
+			if tc.wantErr {
+				require.True(t, errors.Is(err, macros.ErrorInvalidCustomMacro), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package macros
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/sqlds/v3"
+)
+
+var macroPattern = regexp.MustCompile(`\$__(\w+)`)
+
+// Interpolate expands the macros in the query. Unlike sqlds.Interpolate every
+// call is replaced at its own position, so a macro whose name prefixes another
+// one (interval and interval_ms) cannot clobber it, and quoted arguments may
+// contain commas and parentheses. Macros nested in arguments are expanded first.
+func Interpolate(query *sqlds.Query, macros sqlds.Macros) (string, error) {
+	rawSQL := query.RawSQL
+	var b strings.Builder
+	last := 0
+	for _, loc := range macroPattern.FindAllStringSubmatchIndex(rawSQL, -1) {
+		if loc[0] < last {
+			continue
+		}
+		name := rawSQL[loc[2]:loc[3]]
+		fn, ok := macros[name]
+		if !ok {
+			continue
+		}
+
+		end := loc[1]
+		var args []string
+		if end < len(rawSQL) && rawSQL[end] == '(' {
+			closing, err := closingParen(rawSQL, end)
+			if err != nil {
+				return rawSQL, sqlds.DownstreamError(fmt.Errorf("$__%s: %w", name, err))
+			}
+			inner, err := Interpolate(query.WithSQL(rawSQL[end+1:closing]), macros)
+			if err != nil {
+				return rawSQL, err
+			}
+			args = splitArgs(inner)
+			end = closing + 1
+		}
+
+		res, err := fn(query.WithSQL(rawSQL), args)
+		if err != nil {
+			return rawSQL, err
+		}
+		b.WriteString(rawSQL[last:loc[0]])
+		b.WriteString(res)
+		last = end
+	}
+	b.WriteString(rawSQL[last:])
+
+	return b.String(), nil
+}
+
+// closingParen returns the index of the parenthesis closing the one at open,
+// ignoring parentheses inside string literals.
+func closingParen(s string, open int) (int, error) {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"':
+			end, err := closingQuote(s, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("missing closing parenthesis")
+}
+
+// closingQuote returns the index of the quote closing the string literal
+// starting at open. MaxCompute escapes quotes inside literals with a backslash.
+func closingQuote(s string, open int) (int, error) {
+	for i := open + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case s[open]:
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string literal")
+}
+
+// splitArgs splits macro arguments on the commas that are neither nested in
+// parentheses nor inside string literals.
+func splitArgs(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	var (
+		args  []string
+		depth int
+		start int
+	)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"':
+			if end, err := closingQuote(s, i); err == nil {
+				i = end
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(s[start:]))
+}
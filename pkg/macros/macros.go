@@ -1,52 +1,102 @@
 package macros
 
 import (
-	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
 	"github.com/grafana/sqlds/v3"
 )
 
-var (
-	ErrorNoArgumentsToMacro           = errors.New("expected minimum of 1 argument. But no argument found")
-	ErrorInsufficientArgumentsToMacro = errors.New("expected number of arguments not matching")
-)
+// Options holds the datasource and query level settings the macros depend on.
+type Options struct {
+	// Location is the timezone time literals are rendered in. UTC is used when nil.
+	Location *time.Location
+	// Custom are the admin defined macros registered next to the built-in ones.
+	Custom []CustomMacro
+	// Metadata looks up table metadata for the macros that depend on it.
+	Metadata Metadata
+	// Variables are the raw template variable values of the query by name.
+	Variables map[string]Variable
+	// TraceID is the trace a single trace query fetches.
+	TraceID string
+}
+
+func (o Options) location() *time.Location {
+	if o.Location == nil {
+		return time.UTC
+	}
+	return o.Location
+}
 
-func invalidArgs(args []string) error {
-	return sqlds.DownstreamError(fmt.Errorf("%w: expected 1 argument, received %d", sqlds.ErrorBadArgumentCount, len(args)))
+// timeLiteral renders t in the configured timezone as a MaxCompute literal.
+// Without a type the literal is an untyped string, otherwise a DATE, DATETIME or
+// TIMESTAMP literal so MaxCompute does not depend on implicit casting.
+func (o Options) timeLiteral(t time.Time, typ string) (string, error) {
+	t = t.In(o.location())
+	switch strings.ToUpper(strings.Trim(strings.TrimSpace(typ), `'"`)) {
+	case "":
+		return fmt.Sprintf("'%s'", t.Format(time.DateTime)), nil
+	case "DATETIME":
+		return fmt.Sprintf("DATETIME'%s'", t.Format(time.DateTime)), nil
+	case "TIMESTAMP":
+		return fmt.Sprintf("TIMESTAMP'%s'", t.Format("2006-01-02 15:04:05.000")), nil
+	case "DATE":
+		return fmt.Sprintf("DATE'%s'", t.Format(time.DateOnly)), nil
+	}
+	return "", sqlds.DownstreamError(fmt.Errorf("unsupported time literal type %s, expected one of DATE, DATETIME or TIMESTAMP", typ))
 }
 
 // Default time filter for SQL based on the query time range.
-// It requires one argument, the time column to filter.
+// It requires one argument, the time column to filter, and optionally the literal type.
 // Example:
 //
-//	$__timeFilter(time) => "time BETWEEN '2006-01-02 15:04:05' AND '2006-01-02 15:04:05'"
-func MacroTimeFilter(query *sqlds.Query, args []string) (string, error) {
-	if len(args) != 1 {
-		return "", invalidArgs(args)
+//	$__timeFilter(time) => "time >= '2006-01-02 15:04:05' AND time <= '2006-01-02 15:04:05'"
+//	$__timeFilter(time, DATETIME) => "time >= DATETIME'2006-01-02 15:04:05' AND time <= DATETIME'2006-01-02 15:04:05'"
+func (o Options) MacroTimeFilter(query *sqlds.Query, args []string) (string, error) {
+	typ := ""
+	if argCount(args) == 2 {
+		typ = args[1]
+	}
+	from, err := o.timeLiteral(query.TimeRange.From, typ)
+	if err != nil {
+		return "", err
 	}
 
-	var (
-		column = args[0]
-		from   = query.TimeRange.From.UTC().Format(time.DateTime)
-		to     = query.TimeRange.To.UTC().Format(time.DateTime)
-	)
+	to, err := o.timeLiteral(query.TimeRange.To, typ)
+	if err != nil {
+		return "", err
+	}
 
-	return fmt.Sprintf("%s >= '%s' AND %s <= '%s'", column, from, column, to), nil
+	column := args[0]
+	return fmt.Sprintf("%s >= %s AND %s <= %s", column, from, column, to), nil
 }
 
 // Default time filter for SQL based on the starting query time range.
-// It requires one argument, the time column to filter.
+// It takes the time column to filter and optionally the literal type.
+// Without arguments it expands to the bare time literal.
 // Example:
 //
-//	$__timeFrom(time) => "time > '2006-01-02 15:04:05'"
-func MacroTimeFrom(query *sqlds.Query, args []string) (string, error) {
-	if len(args) != 1 {
-		return "", invalidArgs(args)
+//	$__timeFrom() => "'2006-01-02 15:04:05'"
+//	$__timeFrom(time) => "time >= '2006-01-02 15:04:05'"
+//	$__timeFrom(time, DATE) => "time >= DATE'2006-01-02'"
+func (o Options) MacroTimeFrom(query *sqlds.Query, args []string) (string, error) {
+	n := argCount(args)
+	typ := ""
+	if n == 2 {
+		typ = args[1]
+	}
+	from, err := o.timeLiteral(query.TimeRange.From, typ)
+	if err != nil {
+		return "", err
+	}
+
+	if n == 0 {
+		return from, nil
 	}
 
-	return fmt.Sprintf("%s >= '%s'", args[0], query.TimeRange.From.UTC().Format(time.DateTime)), nil
+	return fmt.Sprintf("%s >= %s", args[0], from), nil
 }
 
 // Default time group for SQL based the given period.
@@ -56,10 +106,6 @@ func MacroTimeFrom(query *sqlds.Query, args []string) (string, error) {
 //
 //	$__timeTo(time, month) => "datepart(year, time), datepart(month, time)'"
 func MacroTimeGroup(_ *sqlds.Query, args []string) (string, error) {
-	if len(args) != 2 {
-		return "", invalidArgs(args)
-	}
-
 	column := args[0]
 
 	res := ""
@@ -84,14 +130,161 @@ func MacroTimeGroup(_ *sqlds.Query, args []string) (string, error) {
 }
 
 // Default time filter for SQL based on the ending query time range.
-// It requires one argument, the time column to filter.
+// It takes the time column to filter and optionally the literal type.
+// Without arguments it expands to the bare time literal.
+// Example:
+//
+//	$__timeTo() => "'2006-01-02 15:04:05'"
+//	$__timeTo(time) => "time <= '2006-01-02 15:04:05'"
+//	$__timeTo(time, TIMESTAMP) => "time <= TIMESTAMP'2006-01-02 15:04:05.000'"
+func (o Options) MacroTimeTo(query *sqlds.Query, args []string) (string, error) {
+	n := argCount(args)
+	typ := ""
+	if n == 2 {
+		typ = args[1]
+	}
+	to, err := o.timeLiteral(query.TimeRange.To, typ)
+	if err != nil {
+		return "", err
+	}
+
+	if n == 0 {
+		return to, nil
+	}
+
+	return fmt.Sprintf("%s <= %s", args[0], to), nil
+}
+
+// Unix epoch time filter for SQL based on the query time range.
+// It requires one argument, the BIGINT column holding epoch seconds.
+// Example:
+//
+//	$__unixEpochFilter(time) => "time >= 1136214245 AND time <= 1136214245"
+func MacroUnixEpochFilter(query *sqlds.Query, args []string) (string, error) {
+	var (
+		column = args[0]
+		from   = query.TimeRange.From.UTC().Unix()
+		to     = query.TimeRange.To.UTC().Unix()
+	)
+	return fmt.Sprintf("%s >= %d AND %s <= %d", column, from, column, to), nil
+}
+
+// Unix epoch time filter for SQL based on the query time range.
+// It requires one argument, the BIGINT column holding epoch milliseconds.
+// Example:
+//
+//	$__unixEpochMsFilter(time) => "time >= 1136214245000 AND time <= 1136214245000"
+func MacroUnixEpochMsFilter(query *sqlds.Query, args []string) (string, error) {
+	var (
+		column = args[0]
+		from   = query.TimeRange.From.UTC().UnixMilli()
+		to     = query.TimeRange.To.UTC().UnixMilli()
+	)
+	return fmt.Sprintf("%s >= %d AND %s <= %d", column, from, column, to), nil
+}
+
+// Unix epoch time filter for SQL based on the query time range.
+// It requires one argument, the BIGINT column holding epoch nanoseconds.
+// Example:
+//
+//	$__unixEpochNanoFilter(time) => "time >= 1136214245000000000 AND time <= 1136214245000000000"
+func MacroUnixEpochNanoFilter(query *sqlds.Query, args []string) (string, error) {
+	var (
+		column = args[0]
+		from   = query.TimeRange.From.UTC().UnixNano()
+		to     = query.TimeRange.To.UTC().UnixNano()
+	)
+	return fmt.Sprintf("%s >= %d AND %s <= %d", column, from, column, to), nil
+}
+
+// Starting query time range as unix epoch seconds.
+// It takes no arguments.
 // Example:
 //
-//	$__timeTo(time) => "time < '2006-01-02 15:04:05'"
-func MacroTimeTo(query *sqlds.Query, args []string) (string, error) {
-	if len(args) != 1 {
-		return "", invalidArgs(args)
+//	$__unixEpochFrom() => "1136214245"
+func MacroUnixEpochFrom(query *sqlds.Query, args []string) (string, error) {
+	return fmt.Sprintf("%d", query.TimeRange.From.UTC().Unix()), nil
+}
+
+// Ending query time range as unix epoch seconds.
+// It takes no arguments.
+// Example:
+//
+//	$__unixEpochTo() => "1136214245"
+func MacroUnixEpochTo(query *sqlds.Query, args []string) (string, error) {
+	return fmt.Sprintf("%d", query.TimeRange.To.UTC().Unix()), nil
+}
+
+// Unix epoch time group for SQL based on the given interval.
+// It requires two arguments, the BIGINT column holding epoch seconds and the interval.
+// Example:
+//
+//	$__unixEpochGroup(time, '5m') => "floor(time / 300) * 300"
+func MacroUnixEpochGroup(_ *sqlds.Query, args []string) (string, error) {
+	interval, err := gtime.ParseInterval(strings.Trim(strings.TrimSpace(args[1]), `'"`))
+	if err != nil {
+		return "", sqlds.DownstreamError(fmt.Errorf("error parsing interval %s: %w", args[1], err))
+	}
+	seconds := int64(interval / time.Second)
+	if seconds <= 0 {
+		return "", sqlds.DownstreamError(fmt.Errorf("interval %s must be at least one second", args[1]))
 	}
+	return fmt.Sprintf("floor(%s / %d) * %d", args[0], seconds, seconds), nil
+}
+
+// Query interval in Grafana's short notation.
+// It takes no arguments.
+// Example:
+//
+//	$__interval => "5m"
+func MacroInterval(query *sqlds.Query, args []string) (string, error) {
+	return formatInterval(query.Interval), nil
+}
+
+// Query interval in milliseconds.
+// It takes no arguments.
+// Example:
+//
+//	$__interval_ms => "300000"
+func MacroIntervalMs(query *sqlds.Query, args []string) (string, error) {
+	return fmt.Sprintf("%d", query.Interval.Milliseconds()), nil
+}
 
-	return fmt.Sprintf("%s <= '%s'", args[0], query.TimeRange.To.UTC().Format(time.DateTime)), nil
+// Length of the query time range in seconds.
+// It takes no arguments.
+// Example:
+//
+//	$__timeRangeSeconds => "3600"
+func MacroTimeRangeSeconds(query *sqlds.Query, args []string) (string, error) {
+	return fmt.Sprintf("%d", int64(query.TimeRange.Duration()/time.Second)), nil
+}
+
+// formatInterval renders d in the largest unit that divides it, the way
+// Grafana renders $__interval.
+func formatInterval(d time.Duration) string {
+	ms := d.Milliseconds()
+	units := []struct {
+		suffix string
+		ms     int64
+	}{
+		{"d", int64(24 * time.Hour / time.Millisecond)},
+		{"h", int64(time.Hour / time.Millisecond)},
+		{"m", int64(time.Minute / time.Millisecond)},
+		{"s", int64(time.Second / time.Millisecond)},
+	}
+	for _, u := range units {
+		if ms != 0 && ms%u.ms == 0 {
+			return fmt.Sprintf("%d%s", ms/u.ms, u.suffix)
+		}
+	}
+	return fmt.Sprintf("%dms", ms)
+}
+
+// argCount returns the number of arguments a macro was called with. sqlds
+// passes both $__macro and $__macro() as a single empty argument.
+func argCount(args []string) int {
+	if len(args) == 1 && strings.TrimSpace(args[0]) == "" {
+		return 0
+	}
+	return len(args)
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
 	"github.com/ManassehZhou/maxcompute-datasource/pkg/maxcompute"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/sqlds/v3"
@@ -42,6 +43,21 @@ func TestInterpolate(t *testing.T) {
 		{input: "select * from foo where $__timeFrom(time)", output: "select * from foo where time >= '2014-11-12 11:45:26'", name: "test timeFrom macro"},
 		{input: "select * from foo where $__timeFrom(cast(sth as timestamp))", output: "select * from foo where cast(sth as timestamp) >= '2014-11-12 11:45:26'", name: "test timeFrom macro"},
 		{input: "select $__timeGroup(time,minute), * from foo", output: "select datepart(time, 'mi') as time_minute,datepart(time, 'hh') as time_hour,datepart(time, 'dd') as time_day,datepart(time, 'mm') as time_month,datepart(time, 'yyyy') as time_year, * from foo", name: "test timeGroup macro"},
+		{input: "select * from foo where $__timeFilter(time, DATETIME)", output: "select * from foo where time >= DATETIME'2014-11-12 11:45:26' AND time <= DATETIME'2015-11-12 11:45:26'", name: "test timeFilter with DATETIME literals"},
+		{input: "select * from foo where $__timeFilter(time, timestamp)", output: "select * from foo where time >= TIMESTAMP'2014-11-12 11:45:26.371' AND time <= TIMESTAMP'2015-11-12 11:45:26.371'", name: "test timeFilter with TIMESTAMP literals"},
+		{input: "select * from foo where $__timeFrom(time, DATE)", output: "select * from foo where time >= DATE'2014-11-12'", name: "test timeFrom with DATE literal"},
+		{input: "select * from foo where $__timeTo(time, 'DATETIME')", output: "select * from foo where time <= DATETIME'2015-11-12 11:45:26'", name: "test timeTo with quoted type"},
+		{input: "select * from foo where $__unixEpochFilter(time)", output: "select * from foo where time >= 1415792726 AND time <= 1447328726", name: "test unixEpochFilter macro"},
+		{input: "select * from foo where $__unixEpochMsFilter(time)", output: "select * from foo where time >= 1415792726371 AND time <= 1447328726371", name: "test unixEpochMsFilter macro"},
+		{input: "select * from foo where $__unixEpochNanoFilter(time)", output: "select * from foo where time >= 1415792726371000000 AND time <= 1447328726371000000", name: "test unixEpochNanoFilter macro"},
+		{input: "select * from foo where time >= $__unixEpochFrom() AND time <= $__unixEpochTo()", output: "select * from foo where time >= 1415792726 AND time <= 1447328726", name: "test unixEpochFrom and unixEpochTo macros"},
+		{input: "select $__unixEpochGroup(time, '5m') as t from foo", output: "select floor(time / 300) * 300 as t from foo", name: "test unixEpochGroup macro"},
+		{input: "select * from foo where time >= $__timeFrom() AND time <= $__timeTo()", output: "select * from foo where time >= '2014-11-12 11:45:26' AND time <= '2015-11-12 11:45:26'", name: "test bare timeFrom and timeTo literals"},
+		{input: "select * from foo where time >= $__timeFrom", output: "select * from foo where time >= '2014-11-12 11:45:26'", name: "test timeFrom without parentheses"},
+		{input: "select $__interval as i from foo", output: "select 5m as i from foo", name: "test interval macro"},
+		{input: "select $__interval_ms as ms from foo", output: "select 300000 as ms from foo", name: "test interval_ms macro"},
+		{input: "select $__timeRangeSeconds as s from foo", output: "select 31536000 as s from foo", name: "test timeRangeSeconds macro"},
+		{input: "select $__unixEpochGroup(time, 1h) as t from foo", output: "select floor(time / 3600) * 3600 as t from foo", name: "test unixEpochGroup macro with unquoted interval"},
 	}
 	for i, tc := range tests {
 		driver := MockDB{}
@@ -52,10 +68,100 @@ func TestInterpolate(t *testing.T) {
 					From: from,
 					To:   to,
 				},
+				Interval: 5 * time.Minute,
 			}
-			interpolatedQuery, err := sqlds.Interpolate(&driver, query)
+			interpolatedQuery, err := macros.Interpolate(query, driver.Macros())
 			require.Nil(t, err)
 			assert.Equal(t, tc.output, interpolatedQuery)
 		})
 	}
 }
+
+func TestInterpolateErrors(t *testing.T) {
+	type test struct {
+		name  string
+		input string
+		err   string
+	}
+	tests := []test{
+		{input: "select * from foo where $__unixEpochFilter()", name: "unixEpochFilter without column", err: "expected 1 argument, received 0"},
+		{input: "select * from foo where $__unixEpochFilter(a, b)", name: "unixEpochFilter with extra argument"},
+		{input: "select * from foo where time >= $__unixEpochFrom(time)", name: "unixEpochFrom with argument"},
+		{input: "select $__unixEpochGroup(time) from foo", name: "unixEpochGroup without interval"},
+		{input: "select $__unixEpochGroup(time, 'abc') from foo", name: "unixEpochGroup with invalid interval"},
+		{input: "select * from foo where $__timeFilter(time, INTERVAL)", name: "timeFilter with unsupported literal type"},
+		{input: "select * from foo where $__timeFrom(time, DATE, x)", name: "timeFrom with extra argument", err: "expected 0 to 2 arguments, received 3"},
+		{input: "select * from foo where $__timeFilter()", name: "timeFilter without column", err: "expected 1 or 2 arguments, received 0"},
+		{input: "select $__timeGroup(time) from foo", name: "timeGroup without period", err: "expected 2 arguments, received 1"},
+		{input: "select $__interval_ms(1) from foo", name: "interval_ms with argument", err: "expected no arguments, received 1"},
+	}
+	for i, tc := range tests {
+		driver := MockDB{}
+		t.Run(fmt.Sprintf("[%d/%d] %s", i+1, len(tests), tc.name), func(t *testing.T) {
+			query := &sqlds.Query{
+				RawSQL:    tc.input,
+				TimeRange: backend.TimeRange{From: time.Now().Add(-time.Hour), To: time.Now()},
+			}
+			_, err := macros.Interpolate(query, driver.Macros())
+			require.Error(t, err)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestTimeMacrosTimezone(t *testing.T) {
+	from, _ := time.Parse("2006-01-02T15:04:05.000Z", "2014-11-12T11:45:26.371Z")
+	to, _ := time.Parse("2006-01-02T15:04:05.000Z", "2015-11-12T23:45:26.371Z")
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	opts := macros.Options{Location: shanghai}
+	query := &sqlds.Query{TimeRange: backend.TimeRange{From: from, To: to}}
+
+	res, err := opts.MacroTimeFilter(query, []string{"time", "DATETIME"})
+	require.NoError(t, err)
+	assert.Equal(t, "time >= DATETIME'2014-11-12 19:45:26' AND time <= DATETIME'2015-11-13 07:45:26'", res)
+
+	res, err = opts.MacroTimeTo(query, []string{"ds", "DATE"})
+	require.NoError(t, err)
+	assert.Equal(t, "ds <= DATE'2015-11-13'", res)
+
+	res, err = opts.MacroTimeFrom(query, []string{"time"})
+	require.NoError(t, err)
+	assert.Equal(t, "time >= '2014-11-12 19:45:26'", res)
+}
+
+func TestInterpolateByPosition(t *testing.T) {
+	from, _ := time.Parse("2006-01-02T15:04:05.000Z", "2014-11-12T11:45:26.371Z")
+	to, _ := time.Parse("2006-01-02T15:04:05.000Z", "2015-11-12T11:45:26.371Z")
+	type test struct {
+		name   string
+		input  string
+		output string
+	}
+	tests := []test{
+		{input: "select $__interval as i, $__interval_ms as ms from foo", output: "select 5m as i, 300000 as ms from foo", name: "macro names sharing a prefix"},
+		{input: "select * from foo where $__timeFilter(time, DATETIME) and t >= $__timeFrom", output: "select * from foo where time >= DATETIME'2014-11-12 11:45:26' AND time <= DATETIME'2015-11-12 11:45:26' and t >= '2014-11-12 11:45:26'", name: "several macros"},
+		{input: "select * from foo where $__timeFilter(coalesce(a, b))", output: "select * from foo where coalesce(a, b) >= '2014-11-12 11:45:26' AND coalesce(a, b) <= '2015-11-12 11:45:26'", name: "nested parentheses"},
+		{input: "select * from foo where $__timeFrom(concat(d, ')'))", output: "select * from foo where concat(d, ')') >= '2014-11-12 11:45:26'", name: "parenthesis inside string literal"},
+		{input: "select $__unixEpochGroup(t, $__interval) from foo", output: "select floor(t / 300) * 300 from foo", name: "nested macro"},
+		{input: "select $__unknown(a) from foo", output: "select $__unknown(a) from foo", name: "unknown macro"},
+	}
+	fns := macros.Options{}.Macros()
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("[%d/%d] %s", i+1, len(tests), tc.name), func(t *testing.T) {
+			query := &sqlds.Query{
+				RawSQL:    tc.input,
+				TimeRange: backend.TimeRange{From: from, To: to},
+				Interval:  5 * time.Minute,
+			}
+			res, err := macros.Interpolate(query, fns)
+			require.NoError(t, err)
+			assert.Equal(t, tc.output, res)
+		})
+	}
+
+	_, err := macros.Interpolate(&sqlds.Query{RawSQL: "select $__timeFilter(time"}, fns)
+	require.ErrorContains(t, err, "missing closing parenthesis")
+}
@@ -0,0 +1,306 @@
+package macros
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/sqlds/v3"
+)
+
+var (
+	ErrorNotPartitioned       = errors.New("table is not partitioned")
+	ErrorMetadataUnavailable  = errors.New("table metadata is not available")
+	ErrorNoPartitions         = errors.New("table has no partitions")
+	ErrorUnknownPartitionName = errors.New("unknown partition column")
+	ErrorTooManyPartitions    = errors.New("too many partitions in range")
+	ErrorInvalidDateFormat    = errors.New("invalid partition date format")
+)
+
+// MaxPartitionsInRange caps the number of values $__partitionsInRange expands to,
+// so a wide dashboard range cannot produce a query MaxCompute refuses to compile.
+const MaxPartitionsInRange = 1000
+
+// Metadata gives the macros access to the table metadata stored in MaxCompute.
+type Metadata interface {
+	// PartitionColumns returns the partition columns of a table in declaration order.
+	PartitionColumns(table string) ([]string, error)
+	// Partitions returns every partition of a table as partition column to value.
+	Partitions(table string) ([]map[string]string, error)
+}
+
+// Latest existing partition of a table, looked up in the table metadata.
+// It requires the table, optionally prefixed with the project, and optionally
+// the partition column, which defaults to the first one. A sub-partition
+// column resolves within the latest partition of the columns declared before it.
+// Example:
+//
+//	$__latestPartition(project.table) => "'20060102'"
+//	$__latestPartition(table, hh) => "'15'"
+func (o Options) MacroLatestPartition(_ *sqlds.Query, args []string) (string, error) {
+	if o.Metadata == nil {
+		return "", sqlds.PluginError(ErrorMetadataUnavailable)
+	}
+
+	table := unquote(args[0])
+	columns, err := o.partitionColumns(table, args)
+	if err != nil {
+		return "", err
+	}
+
+	partitions, err := o.Metadata.Partitions(table)
+	if err != nil {
+		return "", sqlds.DownstreamError(fmt.Errorf("could not list partitions of %s: %w", table, err))
+	}
+
+	var latest map[string]string
+	for _, p := range partitions {
+		if _, ok := p[columns[len(columns)-1]]; !ok {
+			continue
+		}
+		if latest == nil || comparePartitions(p, latest, columns) > 0 {
+			latest = p
+		}
+	}
+	if latest == nil {
+		return "", sqlds.DownstreamError(fmt.Errorf("%s: %w", table, ErrorNoPartitions))
+	}
+
+	return quoteString(latest[columns[len(columns)-1]]), nil
+}
+
+// partitionColumns resolves the partition columns of the table up to and
+// including the one named in args[1], or only the first one when it is omitted.
+func (o Options) partitionColumns(table string, args []string) ([]string, error) {
+	columns, err := o.Metadata.PartitionColumns(table)
+	if err != nil {
+		return nil, sqlds.DownstreamError(fmt.Errorf("could not load %s: %w", table, err))
+	}
+	if len(columns) == 0 {
+		return nil, sqlds.DownstreamError(fmt.Errorf("%s: %w", table, ErrorNotPartitioned))
+	}
+	if len(args) < 2 {
+		return columns[:1], nil
+	}
+
+	column := unquote(args[1])
+	for i, c := range columns {
+		if strings.EqualFold(c, column) {
+			return columns[:i+1], nil
+		}
+	}
+	return nil, sqlds.DownstreamError(fmt.Errorf("%w %s of %s, expected one of %s", ErrorUnknownPartitionName, column, table, strings.Join(columns, ", ")))
+}
+
+// Partition values covering the query time range, rendered with a date format
+// for the given granularity, one of hour, day or month. When the table is given
+// the list is intersected with the partitions that exist in the table metadata.
+// Example:
+//
+//	$__partitionsInRange(ds, 'yyyyMMdd', 'day') => "ds IN ('20060101', '20060102')"
+//	$__partitionsInRange(ds, 'yyyyMMdd', 'day', project.table) => "ds IN ('20060102')"
+func (o Options) MacroPartitionsInRange(query *sqlds.Query, args []string) (string, error) {
+	column := args[0]
+	pattern := unquote(args[1])
+	layout, err := dateLayout(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	values, err := o.partitionValues(query.TimeRange.From, query.TimeRange.To, layout, unquote(args[2]), patternGranularity(pattern))
+	if err != nil {
+		return "", err
+	}
+
+	if argCount(args) == 4 {
+		values, err = o.existingPartitions(unquote(args[3]), unquote(column), values)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if len(values) == 0 {
+		return "false", nil
+	}
+
+	return fmt.Sprintf("%s IN (%s)", column, quoteValues(values)), nil
+}
+
+// partitionGranularities are the steps of the partition values, finest first.
+var partitionGranularities = []string{"hour", "day", "month", "year"}
+
+// patternGranularity returns the finest granularity a date pattern renders,
+// so the values of a yyyyMMdd pattern step by day even for an hour granularity.
+func patternGranularity(pattern string) string {
+	switch {
+	case strings.Contains(pattern, "HH"):
+		return "hour"
+	case strings.Contains(pattern, "dd"):
+		return "day"
+	case strings.Contains(pattern, "MM"):
+		return "month"
+	}
+	return "year"
+}
+
+// partitionValues formats every step between from and to in the configured
+// timezone. It steps by the coarser of the granularity and the one of the
+// layout, and fails once more than MaxPartitionsInRange distinct values are produced.
+func (o Options) partitionValues(from, to time.Time, layout, granularity, layoutGranularity string) ([]string, error) {
+	from, to = from.In(o.location()), to.In(o.location())
+
+	step := strings.ToLower(granularity)
+	level := slices.Index(partitionGranularities, step)
+	if level < 0 || step == "year" {
+		return nil, sqlds.DownstreamError(fmt.Errorf("unsupported partition granularity %s, expected one of hour, day or month", granularity))
+	}
+	if slices.Index(partitionGranularities, layoutGranularity) > level {
+		step = layoutGranularity
+	}
+
+	var next func(time.Time) time.Time
+	switch step {
+	case "hour":
+		from = time.Date(from.Year(), from.Month(), from.Day(), from.Hour(), 0, 0, 0, from.Location())
+		next = func(t time.Time) time.Time { return t.Add(time.Hour) }
+	case "day":
+		from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case "month":
+		from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+		next = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	default:
+		from = time.Date(from.Year(), 1, 1, 0, 0, 0, 0, from.Location())
+		next = func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }
+	}
+
+	var res []string
+	for t := from; !t.After(to); t = next(t) {
+		v := t.Format(layout)
+		if len(res) > 0 && res[len(res)-1] == v {
+			continue
+		}
+		res = append(res, v)
+		if len(res) > MaxPartitionsInRange {
+			return nil, sqlds.DownstreamError(fmt.Errorf("%w: more than %d %s partitions, narrow the time range or use a coarser granularity", ErrorTooManyPartitions, MaxPartitionsInRange, granularity))
+		}
+	}
+	return res, nil
+}
+
+// existingPartitions keeps the values of column that exist as partitions of table.
+func (o Options) existingPartitions(table, column string, values []string) ([]string, error) {
+	if o.Metadata == nil {
+		return nil, sqlds.PluginError(ErrorMetadataUnavailable)
+	}
+
+	columns, err := o.partitionColumns(table, []string{table, column})
+	if err != nil {
+		return nil, err
+	}
+	column = columns[len(columns)-1]
+
+	partitions, err := o.Metadata.Partitions(table)
+	if err != nil {
+		return nil, sqlds.DownstreamError(fmt.Errorf("could not list partitions of %s: %w", table, err))
+	}
+
+	existing := map[string]bool{}
+	for _, p := range partitions {
+		if v, ok := p[column]; ok {
+			existing[v] = true
+		}
+	}
+
+	res := values[:0]
+	for _, v := range values {
+		if existing[v] {
+			res = append(res, v)
+		}
+	}
+	return res, nil
+}
+
+// dateLayouts maps the date pattern letters MaxCompute users know from
+// to_char and Java to Go layout elements.
+var dateLayouts = map[string]string{
+	"yyyy": "2006",
+	"yy":   "06",
+	"MM":   "01",
+	"dd":   "02",
+	"HH":   "15",
+	"mm":   "04",
+	"ss":   "05",
+}
+
+// dateLayout converts a date pattern such as yyyyMMdd or yyyy-MM-dd HH into a Go layout.
+func dateLayout(pattern string) (string, error) {
+	if pattern == "" {
+		return "", sqlds.DownstreamError(fmt.Errorf("%w: empty pattern", ErrorInvalidDateFormat))
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			if '0' <= c && c <= '9' {
+				return "", sqlds.DownstreamError(fmt.Errorf("%w: digits are not allowed in %s", ErrorInvalidDateFormat, pattern))
+			}
+			b.WriteByte(c)
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(pattern) && pattern[j] == c {
+			j++
+		}
+		layout, ok := dateLayouts[pattern[i:j]]
+		if !ok {
+			return "", sqlds.DownstreamError(fmt.Errorf("%w: unsupported element %s in %s, expected yyyy, yy, MM, dd, HH, mm or ss", ErrorInvalidDateFormat, pattern[i:j], pattern))
+		}
+		b.WriteString(layout)
+		i = j
+	}
+	return b.String(), nil
+}
+
+// comparePartitions orders two partitions by the given columns in turn.
+func comparePartitions(a, b map[string]string, columns []string) int {
+	for _, c := range columns {
+		if cmp := comparePartitionValues(a[c], b[c]); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// comparePartitionValues orders partition values numerically when both are
+// integers, so hour partitions like 9 and 10 compare correctly, and as strings otherwise.
+func comparePartitionValues(a, b string) int {
+	x, errA := strconv.ParseInt(a, 10, 64)
+	y, errB := strconv.ParseInt(b, 10, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+// unquote strips the quotes around a macro argument.
+func unquote(arg string) string {
+	return strings.Trim(strings.TrimSpace(arg), `'"`+"`")
+}
+
+// quoteString renders s as a MaxCompute string literal.
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,152 @@
+package macros_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/sqlds/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMetadata serves partitions keyed by table name.
+type fakeMetadata struct {
+	columns    map[string][]string
+	partitions map[string][]map[string]string
+}
+
+func (f fakeMetadata) PartitionColumns(table string) ([]string, error) {
+	columns, ok := f.columns[table]
+	if !ok {
+		return nil, errors.New("table not found")
+	}
+	return columns, nil
+}
+
+func (f fakeMetadata) Partitions(table string) ([]map[string]string, error) {
+	return f.partitions[table], nil
+}
+
+var testMetadata = fakeMetadata{
+	columns: map[string][]string{
+		"proj.daily": {"ds"},
+		"hourly":     {"ds", "hh"},
+		"flat":       {},
+		"proj.empty": {"ds"},
+	},
+	partitions: map[string][]map[string]string{
+		"proj.daily": {{"ds": "20240102"}, {"ds": "20240110"}, {"ds": "20231231"}},
+		"hourly":     {{"ds": "20240101", "hh": "9"}, {"ds": "20240101", "hh": "10"}, {"ds": "20231231", "hh": "23"}},
+	},
+}
+
+func TestLatestPartition(t *testing.T) {
+	fns := macros.Options{Metadata: testMetadata}.Macros()
+	interpolate := func(sql string) (string, error) {
+		return macros.Interpolate(&sqlds.Query{RawSQL: sql}, fns)
+	}
+
+	res, err := interpolate("select * from proj.daily where ds = $__latestPartition(proj.daily)")
+	require.NoError(t, err)
+	require.Equal(t, "select * from proj.daily where ds = '20240110'", res)
+
+	res, err = interpolate("select * from hourly where hh = $__latestPartition(hourly, hh)")
+	require.NoError(t, err)
+	require.Equal(t, "select * from hourly where hh = '10'", res)
+
+	res, err = interpolate("select * from hourly where ds = $__latestPartition('hourly')")
+	require.NoError(t, err)
+	require.Equal(t, "select * from hourly where ds = '20240101'", res)
+
+	_, err = interpolate("$__latestPartition(flat)")
+	require.True(t, errors.Is(err, macros.ErrorNotPartitioned), "got %v", err)
+
+	_, err = interpolate("$__latestPartition(proj.empty)")
+	require.True(t, errors.Is(err, macros.ErrorNoPartitions), "got %v", err)
+
+	_, err = interpolate("$__latestPartition(hourly, region)")
+	require.True(t, errors.Is(err, macros.ErrorUnknownPartitionName), "got %v", err)
+
+	_, err = interpolate("$__latestPartition(missing)")
+	require.ErrorContains(t, err, "table not found")
+
+	_, err = macros.Interpolate(&sqlds.Query{RawSQL: "$__latestPartition(hourly)"}, macros.Options{}.Macros())
+	require.True(t, errors.Is(err, macros.ErrorMetadataUnavailable), "got %v", err)
+}
+
+func TestPartitionsInRange(t *testing.T) {
+	from := time.Date(2024, 1, 30, 22, 30, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 2, 1, 15, 0, 0, time.UTC)
+	query := func(sql string) *sqlds.Query {
+		return &sqlds.Query{RawSQL: sql, TimeRange: backend.TimeRange{From: from, To: to}}
+	}
+	fns := macros.Options{Metadata: testMetadata}.Macros()
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"$__partitionsInRange(ds, 'yyyyMMdd', 'day')", "ds IN ('20240130', '20240131', '20240201', '20240202')"},
+		{"$__partitionsInRange(ds, 'yyyy-MM', month)", "ds IN ('2024-01', '2024-02')"},
+		{"$__partitionsInRange(hh, 'yyyyMMddHH', 'hour')", "hh IN ('2024013022', '2024013023', '2024013100', '2024013101', '2024013102', '2024013103', '2024013104', '2024013105', '2024013106', '2024013107', '2024013108', '2024013109', '2024013110', '2024013111', '2024013112', '2024013113', '2024013114', '2024013115', '2024013116', '2024013117', '2024013118', '2024013119', '2024013120', '2024013121', '2024013122', '2024013123', '2024020100', '2024020101', '2024020102', '2024020103', '2024020104', '2024020105', '2024020106', '2024020107', '2024020108', '2024020109', '2024020110', '2024020111', '2024020112', '2024020113', '2024020114', '2024020115', '2024020116', '2024020117', '2024020118', '2024020119', '2024020120', '2024020121', '2024020122', '2024020123', '2024020200', '2024020201')"},
+		{"$__partitionsInRange(ds, 'yyyyMM', 'day')", "ds IN ('202401', '202402')"},
+		{"$__partitionsInRange(ds, 'yyyyMMdd', 'hour')", "ds IN ('20240130', '20240131', '20240201', '20240202')"},
+		{"$__partitionsInRange(ds, 'yyyyMMdd', 'day', proj.empty)", "false"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			res, err := macros.Interpolate(query(tc.input), fns)
+			require.NoError(t, err)
+			require.Equal(t, tc.output, res)
+		})
+	}
+
+	res, err := macros.Interpolate(&sqlds.Query{
+		RawSQL:    "$__partitionsInRange(ds, 'yyyyMMdd', 'day', proj.daily)",
+		TimeRange: backend.TimeRange{From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+	}, fns)
+	require.NoError(t, err)
+	require.Equal(t, "ds IN ('20240102', '20240110')", res)
+
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	res, err = macros.Interpolate(query("$__partitionsInRange(ds, 'yyyyMMdd', 'day')"), macros.Options{Location: shanghai}.Macros())
+	require.NoError(t, err)
+	require.Equal(t, "ds IN ('20240131', '20240201', '20240202')", res)
+
+	_, err = macros.Interpolate(&sqlds.Query{
+		RawSQL:    "$__partitionsInRange(ds, 'yyyyMMddHH', 'hour')",
+		TimeRange: backend.TimeRange{From: from, To: from.AddDate(1, 0, 0)},
+	}, fns)
+	require.True(t, errors.Is(err, macros.ErrorTooManyPartitions), "got %v", err)
+
+	// the cap counts distinct values, so exactly MaxPartitionsInRange days fit
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	res, err = macros.Interpolate(&sqlds.Query{
+		RawSQL:    "$__partitionsInRange(ds, 'yyyyMMdd', 'hour')",
+		TimeRange: backend.TimeRange{From: start, To: start.AddDate(0, 0, macros.MaxPartitionsInRange-1).Add(23 * time.Hour)},
+	}, fns)
+	require.NoError(t, err)
+	require.Equal(t, macros.MaxPartitionsInRange, strings.Count(res, ",")+1)
+
+	_, err = macros.Interpolate(&sqlds.Query{
+		RawSQL:    "$__partitionsInRange(ds, 'yyyyMMdd', 'day')",
+		TimeRange: backend.TimeRange{From: start, To: start.AddDate(0, 0, macros.MaxPartitionsInRange)},
+	}, fns)
+	require.True(t, errors.Is(err, macros.ErrorTooManyPartitions), "got %v", err)
+
+	_, err = macros.Interpolate(query("$__partitionsInRange(ds, 'yyyyMMdd', 'week')"), fns)
+	require.ErrorContains(t, err, "unsupported partition granularity week")
+
+	_, err = macros.Interpolate(query("$__partitionsInRange(ds, 'yyyyMMDD', 'day')"), fns)
+	require.True(t, errors.Is(err, macros.ErrorInvalidDateFormat), "got %v", err)
+
+	_, err = macros.Interpolate(query("$__partitionsInRange(region, 'yyyyMMdd', 'day', hourly)"), fns)
+	require.True(t, errors.Is(err, macros.ErrorUnknownPartitionName), "got %v", err)
+
+	_, err = macros.Interpolate(query("$__partitionsInRange(ds, 'yyyyMMdd', 'day', hourly)"), macros.Options{}.Macros())
+	require.True(t, errors.Is(err, macros.ErrorMetadataUnavailable), "got %v", err)
+}
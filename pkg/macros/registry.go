@@ -0,0 +1,309 @@
+package macros
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/grafana/sqlds/v3"
+)
+
+var (
+	ErrorNoArgumentsToMacro           = errors.New("no argument found")
+	ErrorInsufficientArgumentsToMacro = errors.New("expected number of arguments not matching")
+	ErrorInvalidMacroArgument         = errors.New("invalid macro argument")
+)
+
+// Argument documents a single macro argument.
+type Argument struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Optional    bool   `json:"optional,omitempty"`
+	// Values lists the accepted values, any value when empty.
+	Values []string `json:"values,omitempty"`
+}
+
+// Macro describes a macro, the arguments it accepts and how it is expanded.
+// The metadata is used both to validate calls and to offer help in the SQL editor.
+type Macro struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MinArgs     int    `json:"minArgs"`
+	// MaxArgs is the maximum number of arguments, -1 when unbounded.
+	MaxArgs int        `json:"maxArgs"`
+	Args    []Argument `json:"args"`
+	Example string     `json:"example"`
+
+	apply func(Options, *sqlds.Query, []string) (string, error)
+}
+
+// plain adapts a macro that does not depend on Options.
+func plain(fn sqlds.MacroFunc) func(Options, *sqlds.Query, []string) (string, error) {
+	return func(_ Options, query *sqlds.Query, args []string) (string, error) {
+		return fn(query, args)
+	}
+}
+
+var (
+	columnArg   = Argument{Name: "column", Description: "The time column to filter"}
+	epochArg    = Argument{Name: "column", Description: "The BIGINT column holding the epoch value"}
+	literalArg  = Argument{Name: "type", Description: "Literal type, one of DATE, DATETIME or TIMESTAMP. Untyped string literal when omitted", Optional: true}
+	intervalArg = Argument{Name: "interval", Description: "Group interval such as '5m' or '1h'"}
+	variableArg = Argument{Name: "variable", Description: "The template variable such as $region"}
+	formatArg   = Argument{Name: "format", Description: "Date pattern of the partition values such as 'yyyyMMdd'"}
+	offsetArg   = Argument{Name: "offset", Description: "How far to shift the time range back, such as '1d' or '7d'"}
+)
+
+// Registry lists the built-in macros.
+var Registry = []Macro{
+	{
+		Name:        "timeFilter",
+		Description: "Filters the column on the dashboard time range.",
+		MinArgs:     1,
+		MaxArgs:     2,
+		Args:        []Argument{columnArg, literalArg},
+		Example:     "$__timeFilter(time, DATETIME)",
+		apply:       Options.MacroTimeFilter,
+	},
+	{
+		Name:        "timeFrom",
+		Description: "Start of the dashboard time range, compared against the column when one is given.",
+		MinArgs:     0,
+		MaxArgs:     2,
+		Args:        []Argument{{Name: "column", Description: "The time column to filter", Optional: true}, literalArg},
+		Example:     "$__timeFrom(time)",
+		apply:       Options.MacroTimeFrom,
+	},
+	{
+		Name:        "timeTo",
+		Description: "End of the dashboard time range, compared against the column when one is given.",
+		MinArgs:     0,
+		MaxArgs:     2,
+		Args:        []Argument{{Name: "column", Description: "The time column to filter", Optional: true}, literalArg},
+		Example:     "$__timeTo(time)",
+		apply:       Options.MacroTimeTo,
+	},
+	{
+		Name:        "timeGroup",
+		Description: "Splits the column into datepart columns down to the given period.",
+		MinArgs:     2,
+		MaxArgs:     2,
+		Args:        []Argument{{Name: "column", Description: "The time column to group"}, {Name: "period", Description: "One of minute, hour, day, month or year", Values: []string{"minute", "hour", "day", "month", "year"}}},
+		Example:     "$__timeGroup(time, hour)",
+		apply:       plain(MacroTimeGroup),
+	},
+	{
+		Name:        "unixEpochFilter",
+		Description: "Filters an epoch seconds column on the dashboard time range.",
+		MinArgs:     1,
+		MaxArgs:     1,
+		Args:        []Argument{epochArg},
+		Example:     "$__unixEpochFilter(event_time)",
+		apply:       plain(MacroUnixEpochFilter),
+	},
+	{
+		Name:        "unixEpochMsFilter",
+		Description: "Filters an epoch milliseconds column on the dashboard time range.",
+		MinArgs:     1,
+		MaxArgs:     1,
+		Args:        []Argument{epochArg},
+		Example:     "$__unixEpochMsFilter(event_time)",
+		apply:       plain(MacroUnixEpochMsFilter),
+	},
+	{
+		Name:        "unixEpochNanoFilter",
+		Description: "Filters an epoch nanoseconds column on the dashboard time range.",
+		MinArgs:     1,
+		MaxArgs:     1,
+		Args:        []Argument{epochArg},
+		Example:     "$__unixEpochNanoFilter(event_time)",
+		apply:       plain(MacroUnixEpochNanoFilter),
+	},
+	{
+		Name:        "unixEpochFrom",
+		Description: "Start of the dashboard time range as epoch seconds.",
+		Example:     "$__unixEpochFrom()",
+		apply:       plain(MacroUnixEpochFrom),
+	},
+	{
+		Name:        "unixEpochTo",
+		Description: "End of the dashboard time range as epoch seconds.",
+		Example:     "$__unixEpochTo()",
+		apply:       plain(MacroUnixEpochTo),
+	},
+	{
+		Name:        "unixEpochGroup",
+		Description: "Rounds an epoch seconds column down to the interval.",
+		MinArgs:     2,
+		MaxArgs:     2,
+		Args:        []Argument{epochArg, intervalArg},
+		Example:     "$__unixEpochGroup(event_time, '5m')",
+		apply:       plain(MacroUnixEpochGroup),
+	},
+	{
+		Name:        "interval",
+		Description: "Query interval in Grafana's short notation, such as 5m.",
+		Example:     "$__interval",
+		apply:       plain(MacroInterval),
+	},
+	{
+		Name:        "interval_ms",
+		Description: "Query interval in milliseconds.",
+		Example:     "$__interval_ms",
+		apply:       plain(MacroIntervalMs),
+	},
+	{
+		Name:        "timeRangeSeconds",
+		Description: "Length of the dashboard time range in seconds.",
+		Example:     "$__timeRangeSeconds",
+		apply:       plain(MacroTimeRangeSeconds),
+	},
+	{
+		Name:        "latestPartition",
+		Description: "Latest existing partition value of a table, looked up in the table metadata.",
+		MinArgs:     1,
+		MaxArgs:     2,
+		Args: []Argument{
+			{Name: "table", Description: "The partitioned table, optionally prefixed with the project"},
+			{Name: "column", Description: "The partition column. Defaults to the first one", Optional: true},
+		},
+		Example: "ds = $__latestPartition(project.table)",
+		apply:   Options.MacroLatestPartition,
+	},
+	{
+		Name:        "partitionsInRange",
+		Description: "Lists the partition values covering the dashboard time range as an IN predicate.",
+		MinArgs:     3,
+		MaxArgs:     4,
+		Args: []Argument{
+			{Name: "column", Description: "The partition column"},
+			formatArg,
+			{Name: "granularity", Description: "One of hour, day or month"},
+			{Name: "table", Description: "Keeps only the partitions that exist in this table", Optional: true},
+		},
+		Example: "$__partitionsInRange(ds, 'yyyyMMdd', 'day')",
+		apply:   Options.MacroPartitionsInRange,
+	},
+	{
+		Name:        "timeFilterShift",
+		Description: "Filters the column on the dashboard time range shifted back by an offset.",
+		MinArgs:     2,
+		MaxArgs:     3,
+		Args:        []Argument{columnArg, offsetArg, literalArg},
+		Example:     "$__timeFilterShift(time, '7d')",
+		apply:       Options.MacroTimeFilterShift,
+	},
+	{
+		Name:        "partitionFilterShift",
+		Description: "Filters the partition column on the dashboard time range shifted back by an offset.",
+		MinArgs:     3,
+		MaxArgs:     3,
+		Args: []Argument{
+			{Name: "column", Description: "The partition column"},
+			formatArg,
+			offsetArg,
+		},
+		Example: "$__partitionFilterShift(ds, 'yyyyMMdd', '1d')",
+		apply:   Options.MacroPartitionFilterShift,
+	},
+	{
+		Name:        "in",
+		Description: "Matches the column against the values of a multi-value variable. Always true when All is selected.",
+		MinArgs:     2,
+		MaxArgs:     2,
+		Args:        []Argument{{Name: "column", Description: "The column to match"}, variableArg},
+		Example:     "$__in(region, $region)",
+		apply:       Options.MacroIn,
+	},
+	{
+		Name:        "values",
+		Description: "Values of a multi-value variable as quoted string literals.",
+		MinArgs:     1,
+		MaxArgs:     1,
+		Args:        []Argument{variableArg},
+		Example:     "region IN ($__values($region))",
+		apply:       Options.MacroValues,
+	},
+	{
+		Name:        "traceId",
+		Description: "Trace ID of a single trace query as a quoted string literal.",
+		MinArgs:     0,
+		MaxArgs:     0,
+		Args:        []Argument{},
+		Example:     "trace_id = $__traceId",
+		apply:       Options.MacroTraceID,
+	},
+}
+
+// arity describes the accepted number of arguments for error messages.
+func (m Macro) arity() string {
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", n)
+	}
+	switch {
+	case m.MaxArgs < 0:
+		return "at least " + plural(m.MinArgs)
+	case m.MaxArgs == 0:
+		return "no arguments"
+	case m.MinArgs == m.MaxArgs:
+		return plural(m.MinArgs)
+	case m.MinArgs+1 == m.MaxArgs:
+		return fmt.Sprintf("%d or %s", m.MinArgs, plural(m.MaxArgs))
+	}
+	return fmt.Sprintf("%d to %s", m.MinArgs, plural(m.MaxArgs))
+}
+
+// validate checks the arguments against the declared arity of the macro and
+// the accepted values of its arguments.
+func (m Macro) validate(args []string) error {
+	n := argCount(args)
+	if n < m.MinArgs || (m.MaxArgs >= 0 && n > m.MaxArgs) {
+		cause := ErrorInsufficientArgumentsToMacro
+		if n == 0 {
+			cause = ErrorNoArgumentsToMacro
+		}
+		return sqlds.DownstreamError(fmt.Errorf("%w: %w: $__%s expected %s, received %d", sqlds.ErrorBadArgumentCount, cause, m.Name, m.arity(), n))
+	}
+
+	for i, arg := range m.Args {
+		if i >= n || len(arg.Values) == 0 || slices.Contains(arg.Values, args[i]) {
+			continue
+		}
+		return sqlds.DownstreamError(fmt.Errorf("%w: $__%s %s %s, expected one of %s", ErrorInvalidMacroArgument, m.Name, arg.Name, args[i], strings.Join(arg.Values, ", ")))
+	}
+	return nil
+}
+
+// Func returns the macro as a sqlds.MacroFunc rendered with the given options.
+func (m Macro) Func(opts Options) sqlds.MacroFunc {
+	return func(query *sqlds.Query, args []string) (string, error) {
+		if err := m.validate(args); err != nil {
+			return "", err
+		}
+		return m.apply(opts, query, args)
+	}
+}
+
+// Registry returns the built-in macros followed by the custom ones.
+func (o Options) Registry() []Macro {
+	res := make([]Macro, 0, len(Registry)+len(o.Custom))
+	res = append(res, Registry...)
+	for _, c := range o.Custom {
+		res = append(res, c.macro())
+	}
+	return res
+}
+
+// Macros returns every registered macro rendered with these options.
+func (o Options) Macros() sqlds.Macros {
+	registry := o.Registry()
+	res := make(sqlds.Macros, len(registry))
+	for _, m := range registry {
+		res[m.Name] = m.Func(o)
+	}
+	return res
+}
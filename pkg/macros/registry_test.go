@@ -0,0 +1,57 @@
+package macros_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
+	"github.com/grafana/sqlds/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	seen := map[string]bool{}
+	for _, m := range macros.Registry {
+		t.Run(m.Name, func(t *testing.T) {
+			require.False(t, seen[m.Name], "duplicate macro")
+			seen[m.Name] = true
+			require.NotEmpty(t, m.Description)
+			require.NotEmpty(t, m.Example)
+			require.LessOrEqual(t, m.MinArgs, len(m.Args))
+			if m.MaxArgs >= 0 {
+				require.Equal(t, m.MaxArgs, len(m.Args))
+			}
+		})
+	}
+}
+
+func TestRegistryValidation(t *testing.T) {
+	fns := macros.Options{}.Macros()
+	query := &sqlds.Query{}
+
+	_, err := fns["timeFilter"](query, []string{""})
+	require.True(t, errors.Is(err, sqlds.ErrorBadArgumentCount))
+	require.True(t, errors.Is(err, macros.ErrorNoArgumentsToMacro))
+	require.ErrorContains(t, err, "$__timeFilter expected 1 or 2 arguments, received 0")
+
+	_, err = fns["unixEpochGroup"](query, []string{"a", "b", "c"})
+	require.True(t, errors.Is(err, macros.ErrorInsufficientArgumentsToMacro))
+	require.ErrorContains(t, err, "$__unixEpochGroup expected 2 arguments, received 3")
+
+	_, err = fns["interval"](query, []string{"1"})
+	require.True(t, errors.Is(err, macros.ErrorInsufficientArgumentsToMacro))
+	require.ErrorContains(t, err, "$__interval expected no arguments, received 1")
+
+	_, err = fns["timeGroup"](query, []string{""})
+	require.True(t, errors.Is(err, macros.ErrorNoArgumentsToMacro))
+	require.ErrorContains(t, err, "$__timeGroup expected 2 arguments, received 0")
+	require.NotContains(t, err.Error(), "minimum of 1")
+
+	_, err = fns["timeGroup"](query, []string{"time", "week"})
+	require.True(t, errors.Is(err, macros.ErrorInvalidMacroArgument))
+	require.ErrorContains(t, err, "$__timeGroup period week, expected one of minute, hour, day, month, year")
+
+	res, err := fns["timeGroup"](query, []string{"time", "year"})
+	require.NoError(t, err)
+	require.Equal(t, "datepart(time, 'yyyy') as time_year", res)
+}
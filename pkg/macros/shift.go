@@ -0,0 +1,129 @@
+package macros
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/grafana/sqlds/v3"
+)
+
+var ErrorInvalidOffset = errors.New("invalid time shift")
+
+var offsetPattern = regexp.MustCompile(`^(-?)(\d+)([dwMy])$`)
+
+// Offset is a time shift such as '1h' or '7d'. Days and longer units shift the
+// calendar date, so a day-over-day comparison keeps the wall-clock time across DST changes.
+type Offset struct {
+	years, months, days int
+	duration            time.Duration
+}
+
+// ParseOffset parses a time shift in Grafana's notation, such as '30m', '1d', '1w', '1M' or '1y'.
+func ParseOffset(s string) (Offset, error) {
+	s = unquote(s)
+	m := offsetPattern.FindStringSubmatch(s)
+	if m == nil {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return Offset{}, sqlds.DownstreamError(fmt.Errorf("%w %s, expected a duration such as '1h', '7d' or '1M'", ErrorInvalidOffset, s))
+		}
+		return Offset{duration: d}, nil
+	}
+
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return Offset{}, sqlds.DownstreamError(fmt.Errorf("%w %s: %w", ErrorInvalidOffset, s, err))
+	}
+	if m[1] == "-" {
+		n = -n
+	}
+
+	switch m[3] {
+	case "d":
+		return Offset{days: n}, nil
+	case "w":
+		return Offset{days: 7 * n}, nil
+	case "M":
+		return Offset{months: n}, nil
+	}
+	return Offset{years: n}, nil
+}
+
+// Before returns t shifted back by the offset.
+func (o Offset) Before(t time.Time) time.Time {
+	return addMonths(t, -12*o.years-o.months).AddDate(0, 0, -o.days).Add(-o.duration)
+}
+
+// After returns t shifted forward by the offset.
+func (o Offset) After(t time.Time) time.Time {
+	return addMonths(t, 12*o.years+o.months).AddDate(0, 0, o.days).Add(o.duration)
+}
+
+// addMonths adds n months to t, clamping the day to the end of the target month
+// so that one month before March 31 is February 29 rather than March 2.
+func addMonths(t time.Time, n int) time.Time {
+	if n == 0 {
+		return t
+	}
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+// Time filter for SQL based on the query time range shifted back by an offset,
+// for week-over-week or day-over-day comparisons.
+// It requires the time column and the offset, and optionally the literal type.
+// Example:
+//
+//	$__timeFilterShift(time, '7d') => "time >= '2005-12-26 15:04:05' AND time <= '2005-12-26 15:04:05'"
+//	$__timeFilterShift(time, '1d', DATETIME) => "time >= DATETIME'2006-01-01 15:04:05' AND time <= DATETIME'2006-01-01 15:04:05'"
+func (o Options) MacroTimeFilterShift(query *sqlds.Query, args []string) (string, error) {
+	offset, err := ParseOffset(args[1])
+	if err != nil {
+		return "", err
+	}
+
+	typ := ""
+	if argCount(args) == 3 {
+		typ = args[2]
+	}
+	from, err := o.timeLiteral(offset.Before(query.TimeRange.From.In(o.location())), typ)
+	if err != nil {
+		return "", err
+	}
+
+	to, err := o.timeLiteral(offset.Before(query.TimeRange.To.In(o.location())), typ)
+	if err != nil {
+		return "", err
+	}
+
+	column := args[0]
+	return fmt.Sprintf("%s >= %s AND %s <= %s", column, from, column, to), nil
+}
+
+// Partition filter for SQL based on the query time range shifted back by an offset.
+// It requires the partition column, the date pattern of its values and the offset.
+// Example:
+//
+//	$__partitionFilterShift(ds, 'yyyyMMdd', '1d') => "ds >= '20060101' AND ds <= '20060101'"
+func (o Options) MacroPartitionFilterShift(query *sqlds.Query, args []string) (string, error) {
+	layout, err := dateLayout(unquote(args[1]))
+	if err != nil {
+		return "", err
+	}
+
+	offset, err := ParseOffset(args[2])
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		column = args[0]
+		from   = offset.Before(query.TimeRange.From.In(o.location())).Format(layout)
+		to     = offset.Before(query.TimeRange.To.In(o.location())).Format(layout)
+	)
+	return fmt.Sprintf("%s >= %s AND %s <= %s", column, quoteString(from), column, quoteString(to)), nil
+}
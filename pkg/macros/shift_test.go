@@ -0,0 +1,84 @@
+package macros_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/sqlds/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOffset(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		offset string
+		before time.Time
+	}{
+		{"'1h'", time.Date(2024, 3, 31, 11, 0, 0, 0, time.UTC)},
+		{"90m", time.Date(2024, 3, 31, 10, 30, 0, 0, time.UTC)},
+		{"'1d'", time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC)},
+		{"'7d'", time.Date(2024, 3, 24, 12, 0, 0, 0, time.UTC)},
+		{"1w", time.Date(2024, 3, 24, 12, 0, 0, 0, time.UTC)},
+		{"1M", time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"1y", time.Date(2023, 3, 31, 12, 0, 0, 0, time.UTC)},
+		{"-1d", time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.offset, func(t *testing.T) {
+			offset, err := macros.ParseOffset(tc.offset)
+			require.NoError(t, err)
+			require.Equal(t, tc.before, offset.Before(now))
+			require.Equal(t, tc.before, offset.Before(offset.After(tc.before)))
+		})
+	}
+
+	offset, err := macros.ParseOffset("1M")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 3, 29, 12, 0, 0, 0, time.UTC), offset.After(time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)))
+	require.Equal(t, time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), offset.After(time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)))
+
+	_, err = macros.ParseOffset("'a week'")
+	require.True(t, errors.Is(err, macros.ErrorInvalidOffset), "got %v", err)
+}
+
+func TestShiftMacros(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	query := &sqlds.Query{TimeRange: backend.TimeRange{
+		From: time.Date(2024, 3, 1, 16, 30, 0, 0, time.UTC),
+		To:   time.Date(2024, 3, 2, 15, 59, 59, 0, time.UTC),
+	}}
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"$__timeFilterShift(time, '7d')", "time >= '2024-02-23 16:30:00' AND time <= '2024-02-24 15:59:59'"},
+		{"$__timeFilterShift(time, '1d', DATETIME)", "time >= DATETIME'2024-02-29 16:30:00' AND time <= DATETIME'2024-03-01 15:59:59'"},
+		{"$__timeFilterShift(time, 1h)", "time >= '2024-03-01 15:30:00' AND time <= '2024-03-02 14:59:59'"},
+		{"$__partitionFilterShift(ds, 'yyyyMMdd', '1d')", "ds >= '20240229' AND ds <= '20240301'"},
+		{"$__partitionFilterShift(ds, 'yyyy-MM', '1M')", "ds >= '2024-02' AND ds <= '2024-02'"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			q := *query
+			q.RawSQL = tc.input
+			res, err := macros.Interpolate(&q, macros.Options{}.Macros())
+			require.NoError(t, err)
+			require.Equal(t, tc.output, res)
+		})
+	}
+
+	q := *query
+	q.RawSQL = "$__partitionFilterShift(ds, 'yyyyMMdd', '7d')"
+	res, err := macros.Interpolate(&q, macros.Options{Location: shanghai}.Macros())
+	require.NoError(t, err)
+	require.Equal(t, "ds >= '20240224' AND ds <= '20240224'", res)
+
+	q.RawSQL = "$__timeFilterShift(time, 'yesterday')"
+	_, err = macros.Interpolate(&q, macros.Options{}.Macros())
+	require.True(t, errors.Is(err, macros.ErrorInvalidOffset), "got %v", err)
+}
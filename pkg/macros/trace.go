@@ -0,0 +1,21 @@
+package macros
+
+import (
+	"errors"
+
+	"github.com/grafana/sqlds/v3"
+)
+
+var ErrorNoTraceID = errors.New("$__traceId needs the trace ID of the query")
+
+// The trace ID of a single trace query as a string literal.
+// It takes no arguments.
+// Example:
+//
+//	$__traceId => "'4bf92f3577b34da6a3ce929d0e0e4736'"
+func (o Options) MacroTraceID(_ *sqlds.Query, _ []string) (string, error) {
+	if o.TraceID == "" {
+		return "", sqlds.DownstreamError(ErrorNoTraceID)
+	}
+	return quoteString(o.TraceID), nil
+}
@@ -0,0 +1,19 @@
+package macros_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
+	"github.com/grafana/sqlds/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceIDMacro(t *testing.T) {
+	res, err := macros.Interpolate(&sqlds.Query{RawSQL: "where trace_id = $__traceId"}, macros.Options{TraceID: "4bf92f35'77"}.Macros())
+	require.NoError(t, err)
+	require.Equal(t, `where trace_id = '4bf92f35\'77'`, res)
+
+	_, err = macros.Interpolate(&sqlds.Query{RawSQL: "where trace_id = $__traceId"}, macros.Options{}.Macros())
+	require.True(t, errors.Is(err, macros.ErrorNoTraceID))
+}
@@ -0,0 +1,93 @@
+package macros
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/sqlds/v3"
+)
+
+var ErrorUnknownVariable = errors.New("unknown template variable")
+
+// Variable holds the raw values of a template variable, sent by the frontend
+// so they can be quoted here instead of being spliced into the SQL as text.
+type Variable struct {
+	Values []string `json:"values"`
+	// All is set when the "All" option is selected.
+	All bool `json:"all,omitempty"`
+}
+
+// Predicate matching the column against the values of a template variable.
+// It requires two arguments, the column and the variable. When "All" is
+// selected the predicate is dropped so it does not restrict the query.
+// Example:
+//
+//	$__in(region, $region) => "region IN ('cn-hangzhou', 'cn-beijing')"
+//	$__in(region, $region) => "true"
+func (o Options) MacroIn(_ *sqlds.Query, args []string) (string, error) {
+	v, err := o.variable(args[1])
+	if err != nil {
+		return "", err
+	}
+
+	if v.All {
+		return "true", nil
+	}
+	if len(v.Values) == 0 {
+		return "false", nil
+	}
+
+	return fmt.Sprintf("%s IN (%s)", args[0], quoteValues(v.Values)), nil
+}
+
+// Values of a template variable as a list of string literals.
+// It requires one argument, the variable.
+// Example:
+//
+//	$__values($region) => "'cn-hangzhou', 'cn-beijing'"
+func (o Options) MacroValues(_ *sqlds.Query, args []string) (string, error) {
+	v, err := o.variable(args[0])
+	if err != nil {
+		return "", err
+	}
+
+	if len(v.Values) == 0 {
+		return "NULL", nil
+	}
+
+	return quoteValues(v.Values), nil
+}
+
+// variable looks up the variable referenced as $name, ${name} or [[name]].
+func (o Options) variable(arg string) (Variable, error) {
+	name := variableName(arg)
+	v, ok := o.Variables[name]
+	if !ok {
+		return Variable{}, sqlds.DownstreamError(fmt.Errorf("%w %s", ErrorUnknownVariable, strings.TrimSpace(arg)))
+	}
+	return v, nil
+}
+
+// variableName strips the template syntax and format suffix from a variable reference.
+func variableName(arg string) string {
+	name := strings.TrimSpace(arg)
+	switch {
+	case strings.HasPrefix(name, "${"):
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "${"), "}")
+	case strings.HasPrefix(name, "[["):
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "[["), "]]")
+	default:
+		name = strings.TrimPrefix(name, "$")
+	}
+	name, _, _ = strings.Cut(name, ":")
+	return name
+}
+
+func quoteValues(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = quoteString(v)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,45 @@
+package macros_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
+	"github.com/grafana/sqlds/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariableMacros(t *testing.T) {
+	fns := macros.Options{Variables: map[string]macros.Variable{
+		"region": {Values: []string{"cn-hangzhou", "cn-beijing"}},
+		"name":   {Values: []string{`o'brien`, `back\slash`, `x\' OR 1=1 --`}},
+		"env":    {Values: []string{"prod", "dev"}, All: true},
+		"none":   {},
+	}}.Macros()
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"where $__in(region, $region)", "where region IN ('cn-hangzhou', 'cn-beijing')"},
+		{"where $__in(region, ${region})", "where region IN ('cn-hangzhou', 'cn-beijing')"},
+		{"where $__in(region, ${region:csv})", "where region IN ('cn-hangzhou', 'cn-beijing')"},
+		{"where $__in(region, [[region]])", "where region IN ('cn-hangzhou', 'cn-beijing')"},
+		{"where $__in(name, $name)", `where name IN ('o\'brien', 'back\\slash', 'x\\\' OR 1=1 --')`},
+		{"where a = 1 AND $__in(env, $env)", "where a = 1 AND true"},
+		{"where $__in(x, $none)", "where false"},
+		{"where region IN ($__values($region))", "where region IN ('cn-hangzhou', 'cn-beijing')"},
+		{"where env IN ($__values($env))", "where env IN ('prod', 'dev')"},
+		{"where x IN ($__values($none))", "where x IN (NULL)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			res, err := macros.Interpolate(&sqlds.Query{RawSQL: tc.input}, fns)
+			require.NoError(t, err)
+			require.Equal(t, tc.output, res)
+		})
+	}
+
+	_, err := macros.Interpolate(&sqlds.Query{RawSQL: "$__in(region, $missing)"}, fns)
+	require.True(t, errors.Is(err, macros.ErrorUnknownVariable), "got %v", err)
+}
@@ -0,0 +1,125 @@
+package maxcompute
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+)
+
+// QueryTypeAnnotation is the query type of the annotation queries of a dashboard.
+const QueryTypeAnnotation = "annotation"
+
+var ErrorAnnotationsNoTextColumn = errors.New("annotation queries need a text or title column")
+
+// Names the columns of an annotation query are detected by, in order of preference.
+var (
+	annotationTimeNames    = []string{"time", "start_time", "time_start"}
+	annotationTimeEndNames = []string{"time_end", "end_time"}
+	annotationTitleNames   = []string{"title"}
+	annotationTextNames    = []string{"text", "description"}
+	annotationTagsNames    = []string{"tags"}
+)
+
+// AnnotationOptions maps the columns of an annotation query. Empty columns are
+// detected by name.
+type AnnotationOptions struct {
+	TimeColumn    string `json:"timeColumn,omitempty"`
+	TimeEndColumn string `json:"timeEndColumn,omitempty"`
+	TitleColumn   string `json:"titleColumn,omitempty"`
+	TextColumn    string `json:"textColumn,omitempty"`
+	TagsColumn    string `json:"tagsColumn,omitempty"`
+}
+
+func isTagsType(t data.FieldType) bool {
+	return isStringType(t) || t == data.FieldTypeJSON || t == data.FieldTypeNullableJSON
+}
+
+// annotationsFrame converts a result into the frame Grafana reads annotations
+// from, with the time, timeEnd, title, text and tags fields. Tags are read
+// from an ARRAY column or a comma separated string.
+func annotationsFrame(frame *data.Frame, opts AnnotationOptions) (*data.Frame, error) {
+	columns, err := resolveColumns(frame, []column{
+		{field: "time", configured: opts.TimeColumn, names: annotationTimeNames, accept: isTimeType, required: true},
+		{field: "timeEnd", configured: opts.TimeEndColumn, names: annotationTimeEndNames, accept: isTimeType},
+		{field: "text", configured: opts.TextColumn, names: annotationTextNames, accept: isStringType},
+		{field: "title", configured: opts.TitleColumn, names: annotationTitleNames, accept: isStringType},
+		{field: "tags", configured: opts.TagsColumn, names: annotationTagsNames, accept: isTagsType},
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, hasText := columns["text"]
+	_, hasTitle := columns["title"]
+	if !hasText && !hasTitle {
+		return nil, sqlds.DownstreamError(ErrorAnnotationsNoTextColumn)
+	}
+
+	rows, err := frame.RowLen()
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []*data.Field
+	for _, name := range []string{"time", "timeEnd", "title", "text"} {
+		if i, ok := columns[name]; ok {
+			frame.Fields[i].Name = name
+			fields = append(fields, frame.Fields[i])
+		}
+	}
+	if tagsIndex, ok := columns["tags"]; ok {
+		tags := data.NewFieldFromFieldType(data.FieldTypeJSON, rows)
+		tags.Name = "tags"
+		for row := 0; row < rows; row++ {
+			values, err := annotationTags(frame.Fields[tagsIndex], row)
+			if err != nil {
+				return nil, sqlds.DownstreamError(err)
+			}
+			b, err := json.Marshal(values)
+			if err != nil {
+				return nil, err
+			}
+			tags.Set(row, json.RawMessage(b))
+		}
+		fields = append(fields, tags)
+	}
+
+	annotations := data.NewFrame(frame.Name, fields...)
+	annotations.Meta = frame.Meta
+	return annotations, nil
+}
+
+// annotationTags reads the tags of a row from an ARRAY value or a comma
+// separated string, dropping empty tags.
+func annotationTags(f *data.Field, row int) ([]string, error) {
+	tags := []string{}
+	v, ok := f.ConcreteAt(row)
+	if !ok {
+		return tags, nil
+	}
+
+	var values []string
+	switch v := v.(type) {
+	case string:
+		values = strings.Split(v, ",")
+	case json.RawMessage:
+		var elements []interface{}
+		if err := json.Unmarshal(v, &elements); err != nil {
+			return nil, fmt.Errorf("tags column %s is neither an ARRAY nor a string", f.Name)
+		}
+		for _, e := range elements {
+			if e != nil {
+				values = append(values, fmt.Sprint(e))
+			}
+		}
+	}
+	for _, tag := range values {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,126 @@
+package maxcompute
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+	"gotest.tools/assert"
+)
+
+func TestAnnotationsFrame(t *testing.T) {
+	t1 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	t2 := time.Date(2024, 3, 1, 10, 5, 0, 0, time.UTC)
+	// the frame fields are renamed, so each case gets its own
+	events := func() []*data.Field {
+		return []*data.Field{
+			data.NewField("id", nil, []int64{1, 2}),
+			data.NewField("Time", nil, []time.Time{t1, t2}),
+			data.NewField("time_end", nil, []*time.Time{&t2, nil}),
+			data.NewField("title", nil, []string{"Deploy web v1.2", "Incident"}),
+			data.NewField("text", nil, []string{"Rolled out to all regions", "Payments down"}),
+			data.NewField("tags", nil, []*json.RawMessage{jsonPtr(`["deploy","web"]`), nil}),
+			data.NewField("labels", nil, []string{"prod, web ,", "prod,payments"}),
+		}
+	}
+
+	tests := []struct {
+		description string
+		fields      []*data.Field
+		opts        AnnotationOptions
+		wantFields  []string
+		wantText    string
+		wantTags    [][]string
+		wantErr     error
+	}{
+		{
+			description: "should detect the annotation columns",
+			fields:      events(),
+			wantFields:  []string{"time", "timeEnd", "title", "text", "tags"},
+			wantText:    "Rolled out to all regions",
+			wantTags:    [][]string{{"deploy", "web"}, {}},
+		},
+		{
+			description: "should map the configured columns",
+			fields:      events(),
+			opts:        AnnotationOptions{TextColumn: "title", TagsColumn: "LABELS"},
+			wantFields:  []string{"time", "timeEnd", "text", "tags"},
+			wantText:    "Deploy web v1.2",
+			wantTags:    [][]string{{"prod", "web"}, {"prod", "payments"}},
+		},
+		{
+			description: "should accept a title without a text",
+			fields: []*data.Field{
+				data.NewField("start_time", nil, []time.Time{t1}),
+				data.NewField("title", nil, []string{"Deploy"}),
+			},
+			wantFields: []string{"time", "title"},
+		},
+		{
+			description: "should capture a result without a time column",
+			fields:      []*data.Field{data.NewField("text", nil, []string{"a"})},
+			wantErr:     ErrorColumnRequired,
+		},
+		{
+			description: "should capture a result without a text or title column",
+			fields:      []*data.Field{data.NewField("time", nil, []time.Time{t1}), data.NewField("tags", nil, []string{"a"})},
+			wantErr:     ErrorAnnotationsNoTextColumn,
+		},
+		{
+			description: "should capture a tags column of the wrong type",
+			fields:      events(),
+			opts:        AnnotationOptions{TagsColumn: "id"},
+			wantErr:     ErrorColumnNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			frame := data.NewFrame("A", tc.fields...)
+			frame.Meta = &data.FrameMeta{ExecutedQueryString: "SELECT * FROM events"}
+			annotations, err := annotationsFrame(frame, tc.opts)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("%s not captured. %v", tc.wantErr, err)
+				}
+				assert.Equal(t, backend.ErrorSourceDownstream, sqlds.ErrorSource(err))
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, "SELECT * FROM events", annotations.Meta.ExecutedQueryString)
+
+			names := make([]string, len(annotations.Fields))
+			for i, f := range annotations.Fields {
+				names[i] = f.Name
+			}
+			assert.DeepEqual(t, tc.wantFields, names)
+			assert.Equal(t, t1, annotations.Fields[0].At(0))
+			if text, i := annotations.FieldByName("text"); i >= 0 {
+				assert.Equal(t, tc.wantText, text.At(0))
+			}
+			for row, want := range tc.wantTags {
+				f, _ := annotations.FieldByName("tags")
+				var tags []string
+				assert.NilError(t, json.Unmarshal(f.At(row).(json.RawMessage), &tags))
+				assert.DeepEqual(t, want, tags)
+			}
+		})
+	}
+}
+
+func TestFormatFramesAnnotations(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("time", nil, []time.Time{time.Unix(0, 0)}),
+		data.NewField("value", nil, []float64{1}),
+		data.NewField("text", nil, []string{"Deploy"}),
+	)
+	q := &sqlds.Query{RefID: "A", Format: sqlds.FormatOptionTimeSeries}
+	frames, err := formatFrames(data.Frames{frame}, q, &QueryModel{QueryType: QueryTypeAnnotation})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(frames))
+	assert.Equal(t, 2, len(frames[0].Fields))
+	assert.Equal(t, "text", frames[0].Fields[1].Name)
+}
@@ -0,0 +1,105 @@
+package maxcompute
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+)
+
+var (
+	ErrorColumnNotFound = errors.New("column not found in the result")
+	ErrorColumnRequired = errors.New("the query format needs a column for")
+)
+
+// column is a field a query format reads from a result. A configured column
+// is looked up by name, ignoring case. Otherwise the column is detected by its
+// names, ignoring case and separators, and with fallback set by the first
+// remaining field of an accepted type. A nil accept takes every type.
+type column struct {
+	field      string
+	configured string
+	names      []string
+	accept     func(data.FieldType) bool
+	fallback   bool
+	required   bool
+}
+
+// resolveColumns maps the field of each column onto the index of its result
+// column, resolving the columns in order so a result column is used once.
+// Optional columns that are not found are left out.
+func resolveColumns(frame *data.Frame, columns []column) (map[string]int, error) {
+	fields := map[string]int{}
+	used := map[int]bool{}
+	for _, c := range columns {
+		i := -1
+		if c.configured != "" {
+			if i = findField(frame, c.configured, used, c.accept); i < 0 {
+				return nil, sqlds.DownstreamError(fmt.Errorf("%w: %s", ErrorColumnNotFound, c.configured))
+			}
+		}
+		if i < 0 {
+			i = detectField(frame, c.names, used, c.accept)
+		}
+		if i < 0 && c.fallback {
+			for j, f := range frame.Fields {
+				if !used[j] && accepts(c.accept, f.Type()) {
+					i = j
+					break
+				}
+			}
+		}
+		if i < 0 {
+			if c.required {
+				return nil, sqlds.DownstreamError(fmt.Errorf("%w %s", ErrorColumnRequired, c.field))
+			}
+			continue
+		}
+		fields[c.field] = i
+		used[i] = true
+	}
+	return fields, nil
+}
+
+// findField returns the index of the field named name, ignoring case, that
+// has an accepted type, or -1.
+func findField(frame *data.Frame, name string, skip map[int]bool, accept func(data.FieldType) bool) int {
+	for i, f := range frame.Fields {
+		if !skip[i] && strings.EqualFold(f.Name, name) && accepts(accept, f.Type()) {
+			return i
+		}
+	}
+	return -1
+}
+
+// detectField returns the index of the first field with one of the names, in
+// order of preference, ignoring case and separators, or -1.
+func detectField(frame *data.Frame, names []string, skip map[int]bool, accept func(data.FieldType) bool) int {
+	for _, name := range names {
+		for i, f := range frame.Fields {
+			if !skip[i] && normalizeName(f.Name) == normalizeName(name) && accepts(accept, f.Type()) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// normalizeName lowercases a column name and drops its separators.
+func normalizeName(name string) string {
+	return strings.NewReplacer("_", "", ".", "", "-", "").Replace(strings.ToLower(name))
+}
+
+func accepts(accept func(data.FieldType) bool, t data.FieldType) bool {
+	return accept == nil || accept(t)
+}
+
+func isTimeType(t data.FieldType) bool {
+	return t == data.FieldTypeTime || t == data.FieldTypeNullableTime
+}
+
+func isStringType(t data.FieldType) bool {
+	return t == data.FieldTypeString || t == data.FieldTypeNullableString
+}
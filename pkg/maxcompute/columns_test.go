@@ -0,0 +1,97 @@
+package maxcompute
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+	"gotest.tools/assert"
+)
+
+func TestResolveColumns(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("id", nil, []int64{1}),
+		data.NewField("Event_Time", nil, []time.Time{{}}),
+		data.NewField("created_at", nil, []time.Time{{}}),
+		data.NewField("msg", nil, []string{"started"}),
+		data.NewField("host", nil, []*string{nil}),
+	)
+
+	tests := []struct {
+		description string
+		columns     []column
+		want        map[string]int
+		wantErr     error
+	}{
+		{
+			description: "should detect columns by name in order of preference",
+			columns:     []column{{field: "time", names: []string{"created_at", "event_time"}, accept: isTimeType}},
+			want:        map[string]int{"time": 2},
+		},
+		{
+			description: "should ignore case and separators when detecting",
+			columns:     []column{{field: "time", names: []string{"eventtime"}, accept: isTimeType}},
+			want:        map[string]int{"time": 1},
+		},
+		{
+			description: "should prefer the configured column",
+			columns:     []column{{field: "time", configured: "CREATED_AT", names: []string{"event_time"}, accept: isTimeType}},
+			want:        map[string]int{"time": 2},
+		},
+		{
+			description: "should use a result column once",
+			columns: []column{
+				{field: "time", names: []string{"event_time"}, accept: isTimeType},
+				{field: "end", names: []string{"event_time", "created_at"}, accept: isTimeType},
+			},
+			want: map[string]int{"time": 1, "end": 2},
+		},
+		{
+			description: "should fall back to the first column of an accepted type",
+			columns:     []column{{field: "body", names: []string{"message"}, accept: isStringType, fallback: true}},
+			want:        map[string]int{"body": 3},
+		},
+		{
+			description: "should accept every type without a type check",
+			columns:     []column{{field: "traceID", names: []string{"id"}}},
+			want:        map[string]int{"traceID": 0},
+		},
+		{
+			description: "should leave out optional columns that are not found",
+			columns:     []column{{field: "level", names: []string{"level"}, accept: isStringType}},
+			want:        map[string]int{},
+		},
+		{
+			description: "should capture a missing required column",
+			columns:     []column{{field: "duration", names: []string{"duration"}, required: true}},
+			wantErr:     ErrorColumnRequired,
+		},
+		{
+			description: "should capture a missing configured column",
+			columns:     []column{{field: "level", configured: "severity", accept: isStringType}},
+			wantErr:     ErrorColumnNotFound,
+		},
+		{
+			description: "should capture a configured column of the wrong type",
+			columns:     []column{{field: "time", configured: "msg", accept: isTimeType}},
+			wantErr:     ErrorColumnNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := resolveColumns(frame, tc.columns)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("%s not captured. %v", tc.wantErr, err)
+				}
+				assert.Equal(t, backend.ErrorSourceDownstream, sqlds.ErrorSource(err))
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package maxcompute
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tableschema"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+var (
+	// singleTablePattern matches a select reading from one table, capturing
+	// the table name. Joins, unions and subqueries are rejected separately.
+	singleTablePattern = regexp.MustCompile("(?is)^\\s*select\\s.+?\\sfrom\\s+([\\w.`]+)(?:\\s+(?:as\\s+)?\\w+)?" +
+		`(?:\s+(?:where|group|having|order|sort|distribute|cluster|limit)\b.*)?\s*;?\s*$`)
+	multiTablePattern = regexp.MustCompile(`(?is)\bjoin\b|\bunion\b|\(\s*select\b|\bfrom\s*\(`)
+	// columnRefPattern matches a select item that is a bare column reference,
+	// optionally qualified and aliased, capturing the column and the alias.
+	columnRefPattern = regexp.MustCompile("(?is)^(?:`?\\w+`?\\.)*`?([a-z_]\\w*|\\*)`?(?:\\s+(?:as\\s+)?`?(\\w+)`?)?$")
+	// unitPattern is the comment convention setting the unit of a column, such as [unit:percent].
+	unitPattern = regexp.MustCompile(`\[unit:\s*([^\]]*?)\s*\]`)
+)
+
+// sourceTable returns the table a simple single table select reads from.
+func sourceTable(rawSQL string) (string, bool) {
+	if multiTablePattern.MatchString(rawSQL) {
+		return "", false
+	}
+	m := singleTablePattern.FindStringSubmatch(rawSQL)
+	if m == nil {
+		return "", false
+	}
+	return strings.ReplaceAll(m[1], "`", ""), true
+}
+
+// selectColumns maps the output names of the select list items that are bare
+// column references to the columns they read, by lower case name. A * item
+// selects every column under its own name.
+func selectColumns(rawSQL string) (columns map[string]string, all bool) {
+	columns = map[string]string{}
+	for _, item := range selectItems(rawSQL) {
+		m := columnRefPattern.FindStringSubmatch(item)
+		if m == nil {
+			continue
+		}
+		if m[1] == "*" {
+			all = true
+			continue
+		}
+		name := m[1]
+		if m[2] != "" {
+			name = m[2]
+		}
+		columns[strings.ToLower(name)] = strings.ToLower(m[1])
+	}
+	return columns, all
+}
+
+// selectItems splits the select list of a query on the commas that are
+// neither nested in parentheses nor inside string literals.
+func selectItems(rawSQL string) []string {
+	list := strings.TrimSpace(rawSQL)
+	if len(list) < len("select") || !strings.EqualFold(list[:len("select")], "select") {
+		return nil
+	}
+	list = list[len("select"):]
+
+	var (
+		items []string
+		depth int
+		start int
+		quote byte
+	)
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && c == ',':
+			items = append(items, strings.TrimSpace(list[start:i]))
+			start = i + 1
+		case depth == 0 && isSpace(c) && len(list) > i+5 && strings.EqualFold(list[i+1:i+5], "from") && isSpace(list[i+5]):
+			items = append(items, strings.TrimSpace(list[start:i]))
+			items[0] = strings.TrimSpace(trimKeyword(trimKeyword(items[0], "distinct"), "all"))
+			return items
+		}
+	}
+	return nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// trimKeyword strips a leading keyword followed by a space, ignoring case.
+func trimKeyword(s, keyword string) string {
+	if len(s) > len(keyword) && strings.EqualFold(s[:len(keyword)], keyword) && isSpace(s[len(keyword)]) {
+		return s[len(keyword)+1:]
+	}
+	return s
+}
+
+// columnComment is a column comment split into its text and unit.
+type columnComment struct {
+	displayName string
+	description string
+	unit        string
+}
+
+// parseComment reads a column comment. Its first line is the display name and
+// the whole text the description, with the [unit:...] tag removed.
+func parseComment(comment string) columnComment {
+	var c columnComment
+	if m := unitPattern.FindStringSubmatch(comment); m != nil {
+		c.unit = m[1]
+	}
+	text := strings.TrimSpace(unitPattern.ReplaceAllString(comment, ""))
+	c.displayName, _, _ = strings.Cut(text, "\n")
+	c.displayName = strings.TrimSpace(c.displayName)
+	if text != c.displayName {
+		c.description = text
+	}
+	return c
+}
+
+// describeFrame sets the display name, description and unit of the fields
+// read from a column of the source table from its comment. Only the select
+// list items that are bare column references are described, so an expression
+// aliased with the name of a column does not get its comment. Frames of
+// queries that do not read from a single table are left as they are.
+func (d *MaxComputeDriver) describeFrame(frame *data.Frame) {
+	if frame.Meta == nil || d.metadata == nil {
+		return
+	}
+	table, ok := sourceTable(frame.Meta.ExecutedQueryString)
+	if !ok {
+		return
+	}
+	schema, err := d.metadata.Schema(table)
+	if err != nil {
+		backend.Logger.Warn("could not load the schema for column comments", "table", table, "error", err)
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Column comments of %s are unavailable: %s", table, err),
+		})
+		return
+	}
+
+	comments := schemaComments(schema)
+	columns, all := selectColumns(frame.Meta.ExecutedQueryString)
+	for _, field := range frame.Fields {
+		name := strings.ToLower(field.Name)
+		column, ok := columns[name]
+		if !ok && all {
+			column, ok = name, true
+		}
+		if !ok {
+			continue
+		}
+		if c, ok := comments[column]; ok {
+			describeField(field, c)
+		}
+	}
+}
+
+// schemaComments returns the parsed comments of the columns of a schema by
+// lower case column name.
+func schemaComments(schema *tableschema.TableSchema) map[string]columnComment {
+	comments := map[string]columnComment{}
+	for _, columns := range [][]tableschema.Column{schema.Columns, schema.PartitionColumns} {
+		for _, c := range columns {
+			if strings.TrimSpace(c.Comment) == "" {
+				continue
+			}
+			comments[strings.ToLower(c.Name)] = parseComment(c.Comment)
+		}
+	}
+	return comments
+}
+
+// describeField merges the comment into the field config, keeping a display
+// name set by the query.
+func describeField(field *data.Field, c columnComment) {
+	config := field.Config
+	if config == nil {
+		config = &data.FieldConfig{}
+	}
+	if config.DisplayName == "" && c.displayName != "" {
+		config.DisplayName = c.displayName
+	}
+	if c.description != "" {
+		config.Description = c.description
+	}
+	if c.unit != "" {
+		config.Unit = c.unit
+	}
+	field.SetConfig(config)
+}
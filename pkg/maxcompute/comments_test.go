@@ -0,0 +1,176 @@
+package maxcompute
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tableschema"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"gotest.tools/assert"
+)
+
+func TestSourceTable(t *testing.T) {
+	tests := []struct {
+		sql   string
+		table string
+	}{
+		{sql: "SELECT gmv_idr_d1 FROM sales", table: "sales"},
+		{sql: "select a, b from proj.sales s where ds = '20240101' limit 10;", table: "proj.sales"},
+		{sql: "SELECT a FROM `sales` AS s\nGROUP BY a ORDER BY a", table: "sales"},
+		{sql: "SELECT count(*) AS n FROM sales WHERE region IN ('a', 'b')", table: "sales"},
+		{sql: "SELECT a FROM sales JOIN regions ON sales.r = regions.r"},
+		{sql: "SELECT a FROM sales UNION ALL SELECT a FROM archive"},
+		{sql: "SELECT a FROM (SELECT a FROM sales) t"},
+		{sql: "SELECT a FROM sales WHERE b IN (SELECT b FROM other)"},
+		{sql: "SELECT 1"},
+		{sql: "INSERT INTO sales SELECT * FROM staging"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.sql, func(t *testing.T) {
+			table, ok := sourceTable(tc.sql)
+			assert.Equal(t, tc.table != "", ok)
+			assert.Equal(t, tc.table, table)
+		})
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	tests := []struct {
+		sql     string
+		columns map[string]string
+		all     bool
+	}{
+		{sql: "SELECT a, t.b, `c` AS d, e f FROM t", columns: map[string]string{"a": "a", "b": "b", "d": "c", "f": "e"}},
+		{sql: "select distinct sum(a) as a, concat(b, ',', 'x from y') as b, c\nfrom t", columns: map[string]string{"c": "c"}},
+		{sql: "SELECT t.*, 1 AS one FROM t", columns: map[string]string{}, all: true},
+		{sql: "SELECT 1", columns: map[string]string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.sql, func(t *testing.T) {
+			columns, all := selectColumns(tc.sql)
+			assert.DeepEqual(t, tc.columns, columns)
+			assert.Equal(t, tc.all, all)
+		})
+	}
+}
+
+func TestParseComment(t *testing.T) {
+	assert.Equal(t, columnComment{displayName: "GMV (IDR)"}, parseComment("GMV (IDR)"))
+	assert.Equal(t, columnComment{displayName: "Conversion rate", unit: "percent"}, parseComment("Conversion rate [unit:percent]"))
+	assert.Equal(t,
+		columnComment{displayName: "GMV D1", description: "GMV D1\nGross merchandise value of the first day", unit: "currencyIDR"},
+		parseComment("[unit: currencyIDR] GMV D1\nGross merchandise value of the first day"))
+}
+
+func newCommentMetadata(schemas map[string]*tableschema.TableSchema) (*Metadata, *int) {
+	calls := 0
+	return &Metadata{
+		project: "default_project",
+		schemas: newTTLCache[*tableschema.TableSchema](metadataTTL),
+		loadSchema: func(project, table string) (*tableschema.TableSchema, error) {
+			calls++
+			schema, ok := schemas[project+"."+table]
+			if !ok {
+				return nil, errors.New("table not found")
+			}
+			return schema, nil
+		},
+	}, &calls
+}
+
+func TestProcessFramesColumnComments(t *testing.T) {
+	metadata, calls := newCommentMetadata(map[string]*tableschema.TableSchema{
+		"default_project.sales": {
+			TableName: "sales",
+			Columns: []tableschema.Column{
+				{Name: "gmv_idr_d1", Comment: "GMV D1 [unit:currencyIDR]"},
+				{Name: "cr", Comment: "Conversion rate\nOrders per visit [unit:percentunit]"},
+				{Name: "region"},
+			},
+			PartitionColumns: []tableschema.Column{{Name: "ds", Comment: "Day"}},
+		},
+	})
+	driver := &MaxComputeDriver{metadata: metadata}
+
+	newFrame := func(sql string) *data.Frame {
+		frame := data.NewFrame("A",
+			data.NewField("GMV_IDR_D1", nil, []float64{1}),
+			data.NewField("cr", nil, []float64{0.5}),
+			data.NewField("region", nil, []string{"a"}),
+			data.NewField("ds", nil, []string{"20240101"}),
+			data.NewField("total", nil, []int64{1}),
+		)
+		frame.Fields[1].SetConfig(&data.FieldConfig{DisplayName: "CR"})
+		frame.Meta = &data.FrameMeta{ExecutedQueryString: sql}
+		return frame
+	}
+
+	frame := newFrame("SELECT gmv_idr_d1, cr, region, ds, 1 AS total FROM sales WHERE ds = '20240101'")
+	_, err := driver.processFrames(&QueryModel{ColumnComments: true}, data.Frames{frame})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, &data.FieldConfig{DisplayName: "GMV D1", Unit: "currencyIDR"}, frame.Fields[0].Config)
+	assert.DeepEqual(t, &data.FieldConfig{DisplayName: "CR", Description: "Conversion rate\nOrders per visit", Unit: "percentunit"}, frame.Fields[1].Config)
+	assert.Assert(t, frame.Fields[2].Config == nil)
+	assert.DeepEqual(t, &data.FieldConfig{DisplayName: "Day"}, frame.Fields[3].Config)
+	assert.Assert(t, frame.Fields[4].Config == nil)
+
+	// only bare column references get a comment, not expressions reusing a column name
+	frame = newFrame("SELECT sum(gmv_idr_d1) AS gmv_idr_d1, s.cr AS cr, `region` AS ds, ds AS total FROM sales s")
+	_, err = driver.processFrames(&QueryModel{ColumnComments: true}, data.Frames{frame})
+	assert.NilError(t, err)
+	assert.Assert(t, frame.Fields[0].Config == nil)
+	assert.DeepEqual(t, &data.FieldConfig{DisplayName: "CR", Description: "Conversion rate\nOrders per visit", Unit: "percentunit"}, frame.Fields[1].Config)
+	assert.Assert(t, frame.Fields[3].Config == nil)
+	assert.DeepEqual(t, &data.FieldConfig{DisplayName: "Day"}, frame.Fields[4].Config)
+
+	frame = newFrame("SELECT DISTINCT * FROM sales")
+	_, err = driver.processFrames(&QueryModel{ColumnComments: true}, data.Frames{frame})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &data.FieldConfig{DisplayName: "GMV D1", Unit: "currencyIDR"}, frame.Fields[0].Config)
+
+	// the schema is cached across queries
+	_, err = driver.processFrames(&QueryModel{ColumnComments: true}, data.Frames{newFrame("SELECT cr FROM sales")})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, *calls)
+
+	// disabled by default and skipped for joins
+	for _, tc := range []struct {
+		model *QueryModel
+		sql   string
+	}{
+		{model: &QueryModel{}, sql: "SELECT gmv_idr_d1 FROM sales"},
+		{model: &QueryModel{ColumnComments: true}, sql: "SELECT gmv_idr_d1 FROM sales JOIN other ON sales.id = other.id"},
+	} {
+		frame := newFrame(tc.sql)
+		_, err = driver.processFrames(tc.model, data.Frames{frame})
+		assert.NilError(t, err)
+		assert.Assert(t, frame.Fields[0].Config == nil)
+	}
+
+	// a failed lookup is reported without failing the query
+	frame = newFrame("SELECT gmv_idr_d1 FROM missing")
+	_, err = driver.processFrames(&QueryModel{ColumnComments: true}, data.Frames{frame})
+	assert.NilError(t, err)
+	assert.Assert(t, frame.Fields[0].Config == nil)
+	assert.Equal(t, 1, len(frame.Meta.Notices))
+	assert.Equal(t, data.NoticeSeverityWarning, frame.Meta.Notices[0].Severity)
+}
+
+func TestSchemaCacheExpiry(t *testing.T) {
+	metadata, calls := newCommentMetadata(map[string]*tableschema.TableSchema{"default_project.sales": {TableName: "sales"}})
+	now := time.Now()
+	metadata.schemas.now = func() time.Time { return now }
+
+	_, err := metadata.Schema("sales")
+	assert.NilError(t, err)
+	_, err = metadata.Schema("default_project.sales")
+	assert.NilError(t, err)
+	assert.Equal(t, 1, *calls)
+
+	now = now.Add(metadataTTL)
+	_, err = metadata.Schema("sales")
+	assert.NilError(t, err)
+	assert.Equal(t, 2, *calls)
+}
@@ -0,0 +1,230 @@
+package maxcompute
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+)
+
+// Datasource wraps sqlds.SQLDatasource, which manages the connections, and
+// runs the queries itself so every query can use its own options for macros,
+// type conversion and post-processing.
+type Datasource struct {
+	*sqlds.SQLDatasource
+	driver         *MaxComputeDriver
+	driverSettings sqlds.DriverSettings
+	instance       backend.DataSourceInstanceSettings
+	connect        func(context.Context, backend.DataSourceInstanceSettings, json.RawMessage) (*sql.DB, error)
+
+	// db is the connection a retried query reconnected with. It replaces the
+	// one sqlds opened, which is kept open for the health checks.
+	mu sync.Mutex
+	db *sql.DB
+}
+
+func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
+	driver := &MaxComputeDriver{}
+	ds := sqlds.NewDatasource(driver)
+	ds.CustomRoutes = driver.routes()
+	if _, err := ds.NewDatasource(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	return &Datasource{
+		SQLDatasource:  ds,
+		driver:         driver,
+		driverSettings: ds.DriverSettings(),
+		instance:       settings,
+		connect:        driver.Connect,
+	}, nil
+}
+
+// QueryData runs every query concurrently and stores the results by RefID.
+// Like sqlds it also returns the errors of the queries when the driver
+// settings ask for them.
+func (ds *Datasource) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	var (
+		res = backend.NewQueryDataResponse()
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+	)
+
+	for _, query := range req.Queries {
+		wg.Add(1)
+		go func(query backend.DataQuery) {
+			defer wg.Done()
+			frames, err := ds.query(ctx, req, query)
+			mu.Lock()
+			res.Responses[query.RefID] = backend.DataResponse{Frames: frames, Error: err, ErrorSource: sqlds.ErrorSource(err)}
+			mu.Unlock()
+		}(query)
+	}
+	wg.Wait()
+
+	if ds.driverSettings.Errors {
+		var errs []error
+		for _, r := range res.Responses {
+			errs = append(errs, r.Error)
+		}
+		return res, errors.Join(errs...)
+	}
+	return res, nil
+}
+
+// query interpolates the macros of a query with its own options, runs it on
+// the connection sqlds manages and converts the rows with its own converters.
+// Like sqlds it applies the query and response hooks of the driver.
+func (ds *Datasource) query(ctx context.Context, req *backend.QueryDataRequest, query backend.DataQuery) (data.Frames, error) {
+	var driver sqlds.Driver = ds.driver
+	if mutator, ok := driver.(sqlds.QueryMutator); ok {
+		ctx, query = mutator.MutateQuery(ctx, query)
+	}
+
+	query, err := ds.driver.interpolate(query)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := GetQueryModel(query)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := req.GetHTTPHeaders()
+	q, err := sqlds.GetQuery(query, headers, ds.driverSettings.ForwardHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := ds.conn(ctx, q, datasourceUID(req.PluginContext.DataSourceInstanceSettings))
+	if err != nil {
+		return errorFrames(q), err
+	}
+
+	var args []interface{}
+	if setter, ok := driver.(sqlds.QueryArgSetter); ok {
+		args = setter.SetQueryArgs(ctx, headers)
+	}
+
+	frames, err := ds.run(ctx, db, q, func(db *sql.DB) (data.Frames, error) {
+		return ds.driver.queryFrames(ctx, db, q, model, args...)
+	})
+	if err != nil {
+		return frames, err
+	}
+
+	if mutator, ok := driver.(sqlds.ResponseMutator); ok {
+		frames, err = mutator.MutateResponse(ctx, frames)
+		if err != nil {
+			return nil, sqlds.PluginError(err)
+		}
+	}
+	return frames, nil
+}
+
+// run runs a query the way sqlds does. A query without results is an empty
+// success. A query that fails with one of the RetryOn messages of the settings
+// or times out is retried on a new connection.
+func (ds *Datasource) run(ctx context.Context, db *sql.DB, q *sqlds.Query, query func(*sql.DB) (data.Frames, error)) (data.Frames, error) {
+	settings := ds.driverSettings
+	frames, err := query(db)
+	if err == nil || errors.Is(err, sqlds.ErrorNoResults) {
+		return frames, nil
+	}
+
+	for i := 0; i < settings.Retries && shouldRetry(settings, err); i++ {
+		backend.Logger.Warn(fmt.Sprintf("query failed: %s. Retrying %d times", err.Error(), i))
+		if db, err = ds.reconnect(ctx, db, q); err != nil {
+			return nil, err
+		}
+		if settings.Pause > 0 {
+			time.Sleep(time.Duration(settings.Pause) * time.Second)
+		}
+		frames, err = query(db)
+		if err == nil || errors.Is(err, sqlds.ErrorNoResults) {
+			return frames, nil
+		}
+	}
+	return frames, err
+}
+
+// conn returns the connection of a query: the one a retried query reconnected
+// with, else the one sqlds opened.
+func (ds *Datasource) conn(ctx context.Context, q *sqlds.Query, uid string) (*sql.DB, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if ds.db != nil {
+		return ds.db, nil
+	}
+	return ds.GetDBFromQuery(ctx, q, uid)
+}
+
+// reconnect replaces the connection a query failed on. A connection another
+// query already replaced it with is reused, and the replaced connection is
+// closed unless sqlds opened it.
+func (ds *Datasource) reconnect(ctx context.Context, failed *sql.DB, q *sqlds.Query) (*sql.DB, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if ds.db != nil && ds.db != failed {
+		return ds.db, nil
+	}
+
+	db, err := ds.connect(ctx, ds.instance, q.ConnectionArgs)
+	if err != nil {
+		return nil, sqlds.DownstreamError(err)
+	}
+	if ds.db != nil {
+		ds.db.Close()
+	}
+	ds.db = db
+	return db, nil
+}
+
+// Dispose closes the connection a retried query reconnected with.
+func (ds *Datasource) Dispose() {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if ds.db != nil {
+		ds.db.Close()
+		ds.db = nil
+	}
+	ds.SQLDatasource.Dispose()
+}
+
+// shouldRetry reports whether a failed query is retried: timeouts always are,
+// query errors when they contain one of the RetryOn messages of the settings.
+func shouldRetry(settings sqlds.DriverSettings, err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if !errors.Is(err, sqlds.ErrorQuery) {
+		return false
+	}
+	for _, msg := range settings.RetryOn {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// datasourceUID identifies the connection sqlds opened for the datasource.
+func datasourceUID(settings *backend.DataSourceInstanceSettings) string {
+	if settings == nil {
+		return ""
+	}
+	if settings.UID == "" {
+		return fmt.Sprintf("%d", settings.ID)
+	}
+	return settings.UID
+}
@@ -0,0 +1,149 @@
+package maxcompute
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/converters"
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/odpstest"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+	"gotest.tools/assert"
+)
+
+func TestShouldRetry(t *testing.T) {
+	settings := sqlds.DriverSettings{RetryOn: []string{"ODPS-0130071"}}
+	tests := []struct {
+		description string
+		err         error
+		retry       bool
+	}{
+		{description: "timeout", err: fmt.Errorf("%w", context.DeadlineExceeded), retry: true},
+		{description: "configured query error", err: fmt.Errorf("%w: ODPS-0130071: semantic analysis exception", sqlds.ErrorQuery), retry: true},
+		{description: "other query error", err: fmt.Errorf("%w: ODPS-0110061: access denied", sqlds.ErrorQuery)},
+		{description: "not a query error", err: errors.New("ODPS-0130071")},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.retry, shouldRetry(settings, tc.err))
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	queryErr := fmt.Errorf("%w: ODPS-0130071: semantic analysis exception", sqlds.ErrorQuery)
+	tests := []struct {
+		description    string
+		settings       sqlds.DriverSettings
+		errs           []error
+		wantQueries    int
+		wantReconnects int
+		wantErr        error
+	}{
+		{
+			description: "should treat no results as an empty success",
+			errs:        []error{sqlds.ErrorNoResults},
+			wantQueries: 1,
+		},
+		{
+			description:    "should retry a configured query error on a new connection",
+			settings:       sqlds.DriverSettings{Retries: 2, RetryOn: []string{"ODPS-0130071"}},
+			errs:           []error{queryErr, nil},
+			wantQueries:    2,
+			wantReconnects: 1,
+		},
+		{
+			description:    "should retry a timeout on a new connection",
+			settings:       sqlds.DriverSettings{Retries: 2},
+			errs:           []error{context.DeadlineExceeded, context.DeadlineExceeded, sqlds.ErrorNoResults},
+			wantQueries:    3,
+			wantReconnects: 2,
+		},
+		{
+			description:    "should stop after the retries",
+			settings:       sqlds.DriverSettings{Retries: 1, RetryOn: []string{"ODPS-0130071"}},
+			errs:           []error{queryErr, queryErr},
+			wantQueries:    2,
+			wantReconnects: 1,
+			wantErr:        sqlds.ErrorQuery,
+		},
+		{
+			description: "should not retry other errors",
+			settings:    sqlds.DriverSettings{Retries: 2, RetryOn: []string{"ODPS-0130071"}},
+			errs:        []error{fmt.Errorf("%w: ODPS-0110061: access denied", sqlds.ErrorQuery)},
+			wantQueries: 1,
+			wantErr:     sqlds.ErrorQuery,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			first := odpstest.Open(t)
+			var reconnects []*sql.DB
+			ds := &Datasource{
+				driverSettings: tc.settings,
+				connect: func(context.Context, backend.DataSourceInstanceSettings, json.RawMessage) (*sql.DB, error) {
+					db := odpstest.Open(t)
+					reconnects = append(reconnects, db)
+					return db, nil
+				},
+			}
+
+			var used []*sql.DB
+			_, err := ds.run(context.Background(), first, &sqlds.Query{}, func(db *sql.DB) (data.Frames, error) {
+				used = append(used, db)
+				return nil, tc.errs[len(used)-1]
+			})
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("%s not captured. %v", tc.wantErr, err)
+				}
+			} else {
+				assert.NilError(t, err)
+			}
+			assert.Equal(t, tc.wantQueries, len(used))
+			assert.Equal(t, tc.wantReconnects, len(reconnects))
+			// every retry runs on the connection it reconnected with
+			assert.Equal(t, first, used[0])
+			for i, db := range reconnects {
+				assert.Equal(t, db, used[i+1])
+			}
+			if len(reconnects) > 0 {
+				db, err := ds.conn(context.Background(), &sqlds.Query{}, "")
+				assert.NilError(t, err)
+				assert.Equal(t, reconnects[len(reconnects)-1], db)
+			}
+		})
+	}
+}
+
+func TestReconnectFailure(t *testing.T) {
+	ds := &Datasource{
+		driverSettings: sqlds.DriverSettings{Retries: 1},
+		connect: func(context.Context, backend.DataSourceInstanceSettings, json.RawMessage) (*sql.DB, error) {
+			return nil, errors.New("invalid endpoint")
+		},
+	}
+	_, err := ds.run(context.Background(), odpstest.Open(t), &sqlds.Query{}, func(*sql.DB) (data.Frames, error) {
+		return nil, context.DeadlineExceeded
+	})
+	assert.ErrorContains(t, err, "invalid endpoint")
+	assert.Equal(t, backend.ErrorSourceDownstream, sqlds.ErrorSource(err))
+}
+
+func TestQueryDataErrors(t *testing.T) {
+	req := &backend.QueryDataRequest{Queries: []backend.DataQuery{
+		{RefID: "A", JSON: json.RawMessage(`{"rawSql": "select 1", "decimalMode": "double"}`)},
+	}}
+	for _, returnErrors := range []bool{false, true} {
+		ds := &Datasource{driver: &MaxComputeDriver{}, driverSettings: sqlds.DriverSettings{Errors: returnErrors}}
+		res, err := ds.QueryData(context.Background(), req)
+		assert.Assert(t, errors.Is(res.Responses["A"].Error, converters.ErrorInvalidDecimalMode))
+		// the errors of the queries are returned only when the settings ask for them
+		assert.Equal(t, returnErrors, errors.Is(err, converters.ErrorInvalidDecimalMode))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"maps"
 	"time"
 
 	"github.com/ManassehZhou/maxcompute-datasource/pkg/converters"
@@ -21,8 +22,14 @@ var (
 )
 
 type MaxComputeDriver struct {
+	settings *Settings
+	metadata *Metadata
+	timeout  time.Duration
 }
 
+// fillMode fills the gaps left when long time series are converted to wide ones.
+var fillMode = &data.FillMissing{Mode: data.FillModeNull}
+
 // Connect connects to the database. It does not need to call `db.Ping()`
 func (*MaxComputeDriver) Connect(_ context.Context, settings backend.DataSourceInstanceSettings, raw json.RawMessage) (*sql.DB, error) {
 	log.DefaultLogger.Debug("Creating MaxCompute instance")
@@ -31,34 +38,65 @@ func (*MaxComputeDriver) Connect(_ context.Context, settings backend.DataSourceI
 		return nil, err
 	}
 
+	if _, err := LoadSettings(settings); err != nil {
+		return nil, err
+	}
+
 	db, err := sql.Open("odps", config.FormatDsn())
 	return db, err
 }
 
 // Settings are read whenever the plugin is initialized, or after the data source settings are updated
-func (*MaxComputeDriver) Settings(_ context.Context, settings backend.DataSourceInstanceSettings) (res sqlds.DriverSettings) {
-	res.FillMode = &data.FillMissing{
-		Mode: data.FillModeNull,
-	}
+func (d *MaxComputeDriver) Settings(_ context.Context, settings backend.DataSourceInstanceSettings) (res sqlds.DriverSettings) {
+	res.FillMode = fillMode
 
 	config, err := LoadMaxComputeConfig(settings)
 	if err != nil {
 		res.Timeout = time.Second * 30
+		return
 	}
 
 	res.Timeout = config.TcpConnectionTimeout
+	d.timeout = res.Timeout
+	d.metadata = NewMetadata(config)
+
+	if s, err := LoadSettings(settings); err == nil {
+		d.settings = s
+	}
 	return
 }
 
-func (*MaxComputeDriver) Macros() sqlds.Macros {
-	return map[string]sqlds.MacroFunc{
-		"timeFrom":   macros.MacroTimeFrom,
-		"timeTo":     macros.MacroTimeTo,
-		"timeFilter": macros.MacroTimeFilter,
-		"timeGroup":  macros.MacroTimeGroup,
+func (d *MaxComputeDriver) Macros() sqlds.Macros {
+	return d.macros(d.datasourceMacroOptions())
+}
+
+// datasourceMacroOptions returns the macro options configured on the datasource.
+func (d *MaxComputeDriver) datasourceMacroOptions() macros.Options {
+	opts := d.settings.MacroOptions()
+	opts.Location = d.location()
+	if d.metadata != nil {
+		opts.Metadata = d.metadata
 	}
+	return opts
+}
+
+// location returns the configured project timezone, UTC when none is set.
+// It only renders the time literals of the macros: DATETIME and TIMESTAMP
+// values are instants the driver already decodes in UTC.
+func (d *MaxComputeDriver) location() *time.Location {
+	return d.settings.Location()
+}
+
+// macros returns the macros rendered with the given options, so a query can
+// override the datasource level ones. The sqlds default macros, such as
+// $__table and $__column, are kept underneath the registry ones.
+func (*MaxComputeDriver) macros(opts macros.Options) sqlds.Macros {
+	res := sqlds.Macros{}
+	maps.Copy(res, sqlds.DefaultMacros)
+	maps.Copy(res, opts.Macros())
+	return res
 }
 
-func (*MaxComputeDriver) Converters() []sqlutil.Converter {
-	return converters.MaxComputeConverters
+func (d *MaxComputeDriver) Converters() []sqlutil.Converter {
+	return converters.ConvertersFor(d.settings.ConverterOptions())
 }
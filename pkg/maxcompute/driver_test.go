@@ -3,6 +3,8 @@ package maxcompute_test
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,9 +14,11 @@ import (
 
 	"github.com/ManassehZhou/maxcompute-datasource/pkg/converters"
 	"github.com/ManassehZhou/maxcompute-datasource/pkg/maxcompute"
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/odpstest"
+	data2 "github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/datatype"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
-	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
 	"github.com/stretchr/testify/require"
 	"gotest.tools/assert"
 )
@@ -82,13 +86,7 @@ func setupConnection(t *testing.T) (*sql.DB, error) {
 	return mc.Connect(context.TODO(), settings, json.RawMessage{})
 }
 
-func TestSql(t *testing.T) {
-	t.Run("type & converter test", func(t *testing.T) {
-		db, err := setupConnection(t)
-		if err != nil {
-			panic(err)
-		}
-		rows, err := db.Query(`
+const typesQuery = `
 		set odps.sql.type.system.odps2=true;
 		select 1Y as tinyint, 
 			2S as smallint, 
@@ -108,10 +106,90 @@ func TestSql(t *testing.T) {
 			array(struct(1, 2), struct(3, 4)) as array,
 			map("k1", "v1","k2","v2") as map,
 			named_struct('x', 1,'y',2) as struct
-		;`)
-		require.NoError(t, err)
+		;`
+
+// typesResult is what the ODPS driver returns for typesQuery in a UTC project.
+func typesResult(t *testing.T) odpstest.Result {
+	pairType := datatype.NewStructType(
+		datatype.NewStructFieldType("col1", datatype.IntType),
+		datatype.NewStructFieldType("col2", datatype.IntType),
+	)
+	pair := func(a, b int32) *data2.Struct {
+		s := data2.NewStructWithTyp(pairType)
+		require.NoError(t, s.SetField("col1", data2.Int(a)))
+		require.NoError(t, s.SetField("col2", data2.Int(b)))
+		return s
+	}
+	arrayType := datatype.NewArrayType(pairType)
+	array := data2.NewArrayWithType(arrayType)
+	array.UnSafeAppend(pair(1, 2), pair(3, 4))
+
+	mapType := datatype.NewMapType(datatype.StringType, datatype.StringType)
+	m := data2.NewMapWithType(mapType)
+	require.NoError(t, m.Set(data2.String("k1"), data2.String("v1")))
+	require.NoError(t, m.Set(data2.String("k2"), data2.String("v2")))
+
+	xyType := datatype.NewStructType(
+		datatype.NewStructFieldType("x", datatype.IntType),
+		datatype.NewStructFieldType("y", datatype.IntType),
+	)
+	xy := data2.NewStructWithTyp(xyType)
+	require.NoError(t, xy.SetField("x", data2.Int(1)))
+	require.NoError(t, xy.SetField("y", data2.Int(2)))
+
+	binary, _ := hex.DecodeString("FA34E10293CB42848573A4E39937F479")
+
+	return odpstest.Result{
+		Columns: []odpstest.Column{
+			{Name: "tinyint", Type: "TINYINT"},
+			{Name: "smallint", Type: "SMALLINT"},
+			{Name: "int", Type: "INT"},
+			{Name: "long", Type: "BIGINT"},
+			{Name: "binary", Type: "BINARY"},
+			{Name: "float", Type: "FLOAT"},
+			{Name: "double", Type: "DOUBLE"},
+			{Name: "decimal", Type: "DECIMAL(38,18)"},
+			{Name: "varchar4", Type: "VARCHAR(4)"},
+			{Name: "char4", Type: "CHAR(4)"},
+			{Name: "string", Type: "STRING"},
+			{Name: "date", Type: "DATE"},
+			{Name: "datetime", Type: "DATETIME"},
+			{Name: "timestamp", Type: "TIMESTAMP"},
+			{Name: "bool", Type: "BOOLEAN"},
+			{Name: "array", Type: arrayType.Name()},
+			{Name: "map", Type: mapType.Name()},
+			{Name: "struct", Type: xyType.Name()},
+		},
+		Rows: [][]driver.Value{{
+			int8(1), int16(2), int(1000), int64(3), binary, float32(3.14), float64(3.14),
+			data2.NewDecimal(38, 18, "3.5"), "abcd", "abcd", "abcd",
+			time.Date(2017, time.November, 11, 0, 0, 0, 0, time.UTC),
+			time.Unix(1510358401, 0),
+			time.Unix(1510358402, 123456789),
+			true, array, m, xy,
+		}},
+	}
+}
+
+// queryTypes runs typesQuery on the project in the environment, or on a local
+// stand-in of the driver when no credentials are set.
+func queryTypes(t *testing.T) *sql.Rows {
+	if os.Getenv("ALIBABACLOUD_ACCESS_KEY_ID") == "" {
+		return odpstest.Query(t, typesResult(t))
+	}
+
+	db, err := setupConnection(t)
+	require.NoError(t, err)
+	rows, err := db.Query(typesQuery)
+	require.NoError(t, err)
+	return rows
+}
+
+func TestSql(t *testing.T) {
+	t.Run("type & converter test", func(t *testing.T) {
+		rows := queryTypes(t)
 
-		frame, err := sqlutil.FrameFromRows(rows, 1, converters.MaxComputeConverters...)
+		frame, err := converters.FrameFromRows(rows, converters.Options{})
 
 		require.NotNil(t, frame)
 		require.NoError(t, err)
@@ -135,12 +213,12 @@ func TestSql(t *testing.T) {
 			{id: 9, typeName: data.FieldTypeNullableString, value: ptrOf(string("abcd"))},
 			{id: 10, typeName: data.FieldTypeNullableString, value: ptrOf(string("abcd"))},
 			{id: 11, typeName: data.FieldTypeNullableTime, value: ptrOf(time.Date(2017, time.November, 11, 00, 00, 00, 00000000, time.UTC).UTC())},
-			// {id: 12, typeName: data.FieldTypeNullableTime, value: ptrOf(time.Date(2017, time.November, 11, 00, 00, 01, 00000000, time.UTC).UTC())},
-			// {id: 13, typeName: data.FieldTypeNullableTime, value: ptrOf(time.Date(2017, time.November, 11, 00, 00, 02, 123456789, time.UTC).UTC())},
+			{id: 12, typeName: data.FieldTypeNullableTime, value: ptrOf(time.Date(2017, time.November, 11, 00, 00, 01, 00000000, time.UTC).UTC())},
+			{id: 13, typeName: data.FieldTypeNullableTime, value: ptrOf(time.Date(2017, time.November, 11, 00, 00, 02, 123456789, time.UTC).UTC())},
 			{id: 14, typeName: data.FieldTypeNullableBool, value: ptrOf(bool(true))},
-			{id: 15, typeName: data.FieldTypeNullableString, value: ptrOf(string("array(struct<col1:1,col2:2>, struct<col1:3,col2:4>)"))},
-			{id: 16, typeName: data.FieldTypeNullableString, value: ptrOf(string("map('k1', 'v1', 'k2', 'v2')"))},
-			{id: 17, typeName: data.FieldTypeNullableString, value: ptrOf(string("struct<x:1,y:2>"))},
+			{id: 15, typeName: data.FieldTypeNullableJSON, value: ptrOf(json.RawMessage(`[{"col1":1,"col2":2},{"col1":3,"col2":4}]`))},
+			{id: 16, typeName: data.FieldTypeNullableJSON, value: ptrOf(json.RawMessage(`{"k1":"v1","k2":"v2"}`))},
+			{id: 17, typeName: data.FieldTypeNullableJSON, value: ptrOf(json.RawMessage(`{"x":1,"y":2}`))},
 		}
 
 		for i, tc := range tests {
@@ -152,6 +230,36 @@ func TestSql(t *testing.T) {
 	})
 }
 
+const shanghaiQuery = `
+		set odps.sql.timezone=Asia/Shanghai;
+		select DATETIME'2017-11-11 08:00:01' as datetime,
+			TIMESTAMP'2017-11-11 08:00:02.123456789' as timestamp
+		;`
+
+func TestSqlNonUTCProject(t *testing.T) {
+	// the driver decodes the values from their epoch, so the wall clocks of a
+	// project in Asia/Shanghai are instants 8 hours before them in UTC
+	var rows *sql.Rows
+	if os.Getenv("ALIBABACLOUD_ACCESS_KEY_ID") == "" {
+		shanghai, err := time.LoadLocation("Asia/Shanghai")
+		require.NoError(t, err)
+		rows = odpstest.Query(t, odpstest.Result{
+			Columns: []odpstest.Column{{Name: "datetime", Type: "DATETIME"}, {Name: "timestamp", Type: "TIMESTAMP"}},
+			Rows:    [][]driver.Value{{time.Unix(1510358401, 0).In(shanghai), time.Unix(1510358402, 123456789).In(shanghai)}},
+		})
+	} else {
+		db, err := setupConnection(t)
+		require.NoError(t, err)
+		rows, err = db.Query(shanghaiQuery)
+		require.NoError(t, err)
+	}
+
+	frame, err := converters.FrameFromRows(rows, converters.Options{})
+	require.NoError(t, err)
+	assert.DeepEqual(t, ptrOf(time.Date(2017, time.November, 11, 0, 0, 1, 0, time.UTC)), frame.Fields[0].At(0))
+	assert.DeepEqual(t, ptrOf(time.Date(2017, time.November, 11, 0, 0, 2, 123456789, time.UTC)), frame.Fields[1].At(0))
+}
+
 func ptrOf[K any](val K) *K {
 	return &val
 }
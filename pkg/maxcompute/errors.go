@@ -8,4 +8,5 @@ var (
 	ErrorMessageInvalidProjectName     = errors.New("invalid project name. Either empty or not set")
 	ErrorMessageInvalidAccessKeyId     = errors.New("access key id is either empty or not set")
 	ErrorMessageInvalidAccessKeySecret = errors.New("access key secret is either empty or not set")
+	ErrorMessageInvalidTimezone        = errors.New("invalid timezone. Expected an IANA name such as Asia/Shanghai")
 )
@@ -0,0 +1,75 @@
+package maxcompute
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+)
+
+// formatOptionAuto is the format of queries that leave the choice to the
+// backend, after the ones sqlds defines.
+const formatOptionAuto sqlds.FormatQueryOption = 4
+
+// formatNames are the names of the formats recorded in the frame metadata.
+var formatNames = map[sqlds.FormatQueryOption]string{
+	sqlds.FormatOptionTimeSeries: "time_series",
+	sqlds.FormatOptionTable:      "table",
+	sqlds.FormatOptionLogs:       "logs",
+	sqlds.FormatOptionTrace:      "trace",
+}
+
+// inferFormat chooses the format of a frame from its columns: a time column
+// with a message-like string column is logs, a time column with numeric
+// columns a time series, and anything else a table. It also returns why.
+func inferFormat(frame *data.Frame) (sqlds.FormatQueryOption, string) {
+	timeIndex := -1
+	for i, f := range frame.Fields {
+		if isTimeType(f.Type()) {
+			timeIndex = i
+			break
+		}
+	}
+	if timeIndex < 0 {
+		return sqlds.FormatOptionTable, "no time column"
+	}
+	timeName := frame.Fields[timeIndex].Name
+
+	if i := detectField(frame, logBodyNames, nil, isStringType); i >= 0 {
+		return sqlds.FormatOptionLogs, fmt.Sprintf("time column %s and message column %s", timeName, frame.Fields[i].Name)
+	}
+
+	var numbers, labels []string
+	for i, f := range frame.Fields {
+		switch {
+		case i == timeIndex:
+		case f.Type().Numeric():
+			numbers = append(numbers, f.Name)
+		case isStringType(f.Type()):
+			labels = append(labels, f.Name)
+		}
+	}
+	if len(numbers) == 0 {
+		return sqlds.FormatOptionTable, fmt.Sprintf("time column %s without numeric columns", timeName)
+	}
+	reason := fmt.Sprintf("time column %s and numeric columns %s", timeName, strings.Join(numbers, ", "))
+	if len(labels) > 0 {
+		reason += fmt.Sprintf(", split into series by %s", strings.Join(labels, ", "))
+	}
+	return sqlds.FormatOptionTimeSeries, reason
+}
+
+// recordFormat adds the inferred format and its reason to the custom metadata of a frame.
+func recordFormat(frame *data.Frame, format sqlds.FormatQueryOption, reason string) {
+	if frame.Meta == nil {
+		frame.Meta = &data.FrameMeta{}
+	}
+	custom, ok := frame.Meta.Custom.(map[string]interface{})
+	if !ok {
+		custom = map[string]interface{}{}
+	}
+	custom["inferredFormat"] = formatNames[format]
+	custom["inferredFormatReason"] = reason
+	frame.Meta.Custom = custom
+}
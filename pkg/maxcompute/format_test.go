@@ -0,0 +1,105 @@
+package maxcompute
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+	"gotest.tools/assert"
+)
+
+func TestInferFormat(t *testing.T) {
+	t1 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		description string
+		frame       *data.Frame
+		format      sqlds.FormatQueryOption
+		reason      string
+	}{
+		{
+			description: "should infer a time series",
+			frame: data.NewFrame("A",
+				data.NewField("ts", nil, []time.Time{t1}),
+				data.NewField("orders", nil, []int64{1}),
+				data.NewField("gmv", nil, []*float64{nil}),
+			),
+			format: sqlds.FormatOptionTimeSeries,
+			reason: "time column ts and numeric columns orders, gmv",
+		},
+		{
+			description: "should infer a long time series",
+			frame: data.NewFrame("A",
+				data.NewField("ts", nil, []*time.Time{&t1}),
+				data.NewField("region", nil, []string{"a"}),
+				data.NewField("orders", nil, []int64{1}),
+			),
+			format: sqlds.FormatOptionTimeSeries,
+			reason: "time column ts and numeric columns orders, split into series by region",
+		},
+		{
+			description: "should infer logs",
+			frame: data.NewFrame("A",
+				data.NewField("event_time", nil, []time.Time{t1}),
+				data.NewField("status", nil, []int64{200}),
+				data.NewField("Message", nil, []string{"GET /"}),
+			),
+			format: sqlds.FormatOptionLogs,
+			reason: "time column event_time and message column Message",
+		},
+		{
+			description: "should infer a table without a time column",
+			frame: data.NewFrame("A",
+				data.NewField("region", nil, []string{"a"}),
+				data.NewField("orders", nil, []int64{1}),
+			),
+			format: sqlds.FormatOptionTable,
+			reason: "no time column",
+		},
+		{
+			description: "should infer a table without numeric columns",
+			frame: data.NewFrame("A",
+				data.NewField("ts", nil, []time.Time{t1}),
+				data.NewField("region", nil, []string{"a"}),
+			),
+			format: sqlds.FormatOptionTable,
+			reason: "time column ts without numeric columns",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			format, reason := inferFormat(tc.frame)
+			assert.Equal(t, tc.format, format)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
+
+func TestFormatFramesAuto(t *testing.T) {
+	t1 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	frame := data.NewFrame("A",
+		data.NewField("ts", nil, []time.Time{t1, t1}),
+		data.NewField("region", nil, []string{"a", "b"}),
+		data.NewField("orders", nil, []int64{1, 2}),
+	)
+	logs := data.NewFrame("B",
+		data.NewField("event_time", nil, []time.Time{t1}),
+		data.NewField("message", nil, []string{"GET /"}),
+	)
+	logs.Meta = &data.FrameMeta{}
+	q := &sqlds.Query{RefID: "A", Format: formatOptionAuto}
+	frames, err := formatFrames(data.Frames{frame, logs}, q, &QueryModel{})
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(frames))
+
+	// the long time series is converted into a wide one
+	assert.Equal(t, 3, len(frames[0].Fields))
+	assert.DeepEqual(t, data.Labels{"region": "a"}, frames[0].Fields[1].Labels)
+	assert.Equal(t, data.VisType(data.VisTypeGraph), frames[0].Meta.PreferredVisualization)
+	custom := frames[0].Meta.Custom.(map[string]interface{})
+	assert.Equal(t, "time_series", custom["inferredFormat"])
+	assert.Equal(t, "time column ts and numeric columns orders, split into series by region", custom["inferredFormatReason"])
+
+	assert.Equal(t, data.FrameTypeLogLines, frames[1].Meta.Type)
+	assert.Equal(t, "logs", frames[1].Meta.Custom.(map[string]interface{})["inferredFormat"])
+}
@@ -0,0 +1,220 @@
+package maxcompute
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+)
+
+// Names the columns of a logs query are detected by, in order of preference.
+var (
+	logTimeNames  = []string{"timestamp", "time", "ts", "event_time", "log_time", "created_at"}
+	logBodyNames  = []string{"body", "message", "msg", "log", "line", "content", "text"}
+	logLevelNames = []string{"level", "severity", "log_level", "loglevel", "lvl", "priority"}
+)
+
+// unknownLevel is the level Grafana shows for log lines without one.
+const unknownLevel = "unknown"
+
+// LogsOptions maps the columns of a logs query. Empty columns are detected by name.
+type LogsOptions struct {
+	TimeColumn  string `json:"timeColumn,omitempty"`
+	BodyColumn  string `json:"bodyColumn,omitempty"`
+	LevelColumn string `json:"levelColumn,omitempty"`
+}
+
+// logsFrame converts a result into a log lines frame of the data plane
+// contract: the time, body and level columns become the timestamp, body and
+// severity fields, and the other columns the labels of each line. Lines
+// without a timestamp are dropped with a notice.
+func logsFrame(frame *data.Frame, opts LogsOptions) (*data.Frame, error) {
+	columns, err := resolveColumns(frame, []column{
+		{field: "timestamp", configured: opts.TimeColumn, names: logTimeNames, accept: isTimeType, fallback: true, required: true},
+		{field: "severity", configured: opts.LevelColumn, names: logLevelNames, accept: isStringType},
+		{field: "body", configured: opts.BodyColumn, names: logBodyNames, accept: isStringType, fallback: true, required: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	used := map[int]bool{}
+	for _, i := range columns {
+		used[i] = true
+	}
+
+	rows, err := frame.RowLen()
+	if err != nil {
+		return nil, err
+	}
+
+	// the logs panel needs a timestamp on every line
+	timestamp := frame.Fields[columns["timestamp"]]
+	var keep []int
+	for row := 0; row < rows; row++ {
+		if _, ok := timestamp.ConcreteAt(row); ok {
+			keep = append(keep, row)
+		}
+	}
+
+	times := make([]time.Time, len(keep))
+	for i, row := range keep {
+		v, _ := timestamp.ConcreteAt(row)
+		times[i] = v.(time.Time)
+	}
+	fields := []*data.Field{data.NewField("timestamp", nil, times)}
+	for _, name := range []string{"body", "severity"} {
+		if i, ok := columns[name]; ok {
+			f := data.NewFieldFromFieldType(frame.Fields[i].Type(), len(keep))
+			f.Name = name
+			for j, row := range keep {
+				f.Set(j, frame.Fields[i].At(row))
+			}
+			fields = append(fields, f)
+		}
+	}
+
+	labels := data.NewFieldFromFieldType(data.FieldTypeJSON, len(keep))
+	labels.Name = "labels"
+	for j, row := range keep {
+		values := map[string]string{}
+		for i, f := range frame.Fields {
+			if used[i] {
+				continue
+			}
+			if v, ok := labelValue(f, row); ok {
+				values[f.Name] = v
+			}
+		}
+		b, err := json.Marshal(values)
+		if err != nil {
+			return nil, err
+		}
+		labels.Set(j, json.RawMessage(b))
+	}
+	fields = append(fields, labels)
+
+	logs := data.NewFrame(frame.Name, fields...)
+	logs.Meta = frame.Meta
+	if logs.Meta == nil {
+		logs.Meta = &data.FrameMeta{}
+	}
+	if dropped := rows - len(keep); dropped > 0 {
+		logs.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("%d log lines without a timestamp were dropped", dropped),
+		})
+	}
+	logs.Meta.Type = data.FrameTypeLogLines
+	logs.Meta.TypeVersion = data.FrameTypeVersion{0, 0}
+	logs.Meta.PreferredVisualization = data.VisTypeLogs
+	return logs, nil
+}
+
+// labelValue renders the value of a field at a row as a label value, false when it is null.
+func labelValue(f *data.Field, row int) (string, bool) {
+	v, ok := f.ConcreteAt(row)
+	if !ok {
+		return "", false
+	}
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	case json.RawMessage:
+		return string(v), true
+	}
+	return fmt.Sprint(v), true
+}
+
+// maxVolumeBuckets caps the buckets of a logs volume when the query does not
+// set a lower maximum number of data points.
+const maxVolumeBuckets = 1000
+
+// volumeInterval returns the bucket size of a logs volume: the query interval,
+// widened so the time range fits in the maximum number of buckets.
+func volumeInterval(q *sqlds.Query) time.Duration {
+	buckets := int64(maxVolumeBuckets)
+	if q.MaxDataPoints > 0 && q.MaxDataPoints < buckets {
+		buckets = q.MaxDataPoints
+	}
+
+	interval := q.Interval
+	span := q.TimeRange.To.Sub(q.TimeRange.From)
+	if min := (span + time.Duration(buckets) - 1) / time.Duration(buckets); interval < min {
+		interval = min
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return interval
+}
+
+// logsVolumeFrames counts the lines of a log lines frame per level in buckets
+// of the query interval over its time range, for the Explore logs volume. As
+// only the returned lines are counted, the volume is marked as limited.
+func logsVolumeFrames(logs *data.Frame, q *sqlds.Query) data.Frames {
+	interval := volumeInterval(q)
+	from := q.TimeRange.From.Truncate(interval)
+	buckets := int(q.TimeRange.To.Sub(from)/interval) + 1
+
+	severity, _ := logs.FieldByName("severity")
+	timestamp := logs.Fields[0]
+
+	counts := map[string][]int64{}
+	for row := 0; row < timestamp.Len(); row++ {
+		v, ok := timestamp.ConcreteAt(row)
+		if !ok {
+			continue
+		}
+		bucket := int(v.(time.Time).Sub(from) / interval)
+		if bucket < 0 || bucket >= buckets {
+			continue
+		}
+		level := unknownLevel
+		if severity != nil {
+			if l, ok := severity.ConcreteAt(row); ok && l.(string) != "" {
+				level = strings.ToLower(l.(string))
+			}
+		}
+		if counts[level] == nil {
+			counts[level] = make([]int64, buckets)
+		}
+		counts[level][bucket]++
+	}
+
+	levels := make([]string, 0, len(counts))
+	for level := range counts {
+		levels = append(levels, level)
+	}
+	sort.Strings(levels)
+
+	times := make([]time.Time, buckets)
+	for i := range times {
+		times[i] = from.Add(time.Duration(i) * interval)
+	}
+
+	frames := make(data.Frames, 0, len(levels))
+	for _, level := range levels {
+		value := data.NewField("value", data.Labels{"level": level}, counts[level])
+		value.SetConfig(&data.FieldConfig{DisplayNameFromDS: level})
+		frame := data.NewFrame(logs.Name, data.NewField("time", nil, append([]time.Time{}, times...)), value)
+		frame.Meta = &data.FrameMeta{
+			ExecutedQueryString:    logs.Meta.ExecutedQueryString,
+			PreferredVisualization: data.VisTypeGraph,
+			Custom: map[string]interface{}{
+				"logsVolumeType": "Limited",
+				"absoluteRange": map[string]int64{
+					"from": q.TimeRange.From.UnixMilli(),
+					"to":   q.TimeRange.To.UnixMilli(),
+				},
+			},
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
@@ -0,0 +1,210 @@
+package maxcompute
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+	"gotest.tools/assert"
+)
+
+func TestLogsFrame(t *testing.T) {
+	t1 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	host := "web-1"
+
+	tests := []struct {
+		description string
+		fields      []*data.Field
+		opts        LogsOptions
+		wantFields  []string
+		wantBody    interface{}
+		wantLevel   string
+		wantLabels  map[string]string
+		wantNotice  string
+		wantErr     error
+	}{
+		{
+			description: "should detect the time, body and level columns",
+			fields: []*data.Field{
+				data.NewField("host", nil, []*string{&host}),
+				data.NewField("event_time", nil, []time.Time{t1}),
+				data.NewField("Message", nil, []string{"POST /pay failed"}),
+				data.NewField("level", nil, []string{"ERROR"}),
+			},
+			wantFields: []string{"timestamp", "body", "severity", "labels"},
+			wantBody:   "POST /pay failed",
+			wantLevel:  "ERROR",
+			wantLabels: map[string]string{"host": "web-1"},
+		},
+		{
+			description: "should map the configured columns",
+			fields: []*data.Field{
+				data.NewField("host", nil, []*string{&host}),
+				data.NewField("event_time", nil, []time.Time{t1}),
+				data.NewField("message", nil, []string{"GET /"}),
+				data.NewField("level", nil, []string{"INFO"}),
+			},
+			opts:       LogsOptions{BodyColumn: "host", LevelColumn: "MESSAGE"},
+			wantFields: []string{"timestamp", "body", "severity", "labels"},
+			wantBody:   &host,
+			wantLevel:  "GET /",
+			wantLabels: map[string]string{"level": "INFO"},
+		},
+		{
+			description: "should keep the other columns as labels without a level",
+			fields: []*data.Field{
+				data.NewField("t", nil, []time.Time{t1}),
+				data.NewField("line", nil, []string{"started"}),
+				data.NewField("pid", nil, []int64{42}),
+				data.NewField("node", nil, []*string{nil}),
+			},
+			wantFields: []string{"timestamp", "body", "labels"},
+			wantBody:   "started",
+			wantLabels: map[string]string{"pid": "42"},
+		},
+		{
+			description: "should drop the lines without a timestamp",
+			fields: []*data.Field{
+				data.NewField("event_time", nil, []*time.Time{nil, &t1, nil}),
+				data.NewField("message", nil, []string{"lost", "kept", "lost"}),
+				data.NewField("host", nil, []*string{nil, &host, nil}),
+			},
+			wantFields: []string{"timestamp", "body", "labels"},
+			wantBody:   "kept",
+			wantLabels: map[string]string{"host": "web-1"},
+			wantNotice: "2 log lines without a timestamp were dropped",
+		},
+		{
+			description: "should capture a result without a time column",
+			fields:      []*data.Field{data.NewField("message", nil, []string{"a"})},
+			wantErr:     ErrorColumnRequired,
+		},
+		{
+			description: "should capture a result without a string column",
+			fields:      []*data.Field{data.NewField("time", nil, []time.Time{t1}), data.NewField("n", nil, []int64{1})},
+			wantErr:     ErrorColumnRequired,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			frame := data.NewFrame("A", tc.fields...)
+			frame.Meta = &data.FrameMeta{ExecutedQueryString: "SELECT * FROM access_logs"}
+			logs, err := logsFrame(frame, tc.opts)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("%s not captured. %v", tc.wantErr, err)
+				}
+				assert.Equal(t, backend.ErrorSourceDownstream, sqlds.ErrorSource(err))
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, data.FrameTypeLogLines, logs.Meta.Type)
+			assert.Equal(t, data.VisType(data.VisTypeLogs), logs.Meta.PreferredVisualization)
+			assert.Equal(t, "SELECT * FROM access_logs", logs.Meta.ExecutedQueryString)
+
+			names := make([]string, len(logs.Fields))
+			for i, f := range logs.Fields {
+				names[i] = f.Name
+			}
+			assert.DeepEqual(t, tc.wantFields, names)
+			assert.Equal(t, 1, logs.Rows())
+			assert.Equal(t, data.FieldTypeTime, logs.Fields[0].Type())
+			assert.Equal(t, t1, logs.Fields[0].At(0))
+			assert.DeepEqual(t, tc.wantBody, logs.Fields[1].At(0))
+			if tc.wantLevel != "" {
+				assert.Equal(t, tc.wantLevel, logs.Fields[2].At(0))
+			}
+
+			var labels map[string]string
+			assert.NilError(t, json.Unmarshal(logs.Fields[len(logs.Fields)-1].At(0).(json.RawMessage), &labels))
+			assert.DeepEqual(t, tc.wantLabels, labels)
+
+			if tc.wantNotice == "" {
+				assert.Equal(t, 0, len(logs.Meta.Notices))
+			} else {
+				assert.Equal(t, 1, len(logs.Meta.Notices))
+				assert.Equal(t, tc.wantNotice, logs.Meta.Notices[0].Text)
+			}
+		})
+	}
+}
+
+func TestLogsVolumeFrames(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("time", nil, []time.Time{
+			time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 1, 10, 0, 30, 0, time.UTC),
+			time.Date(2024, 3, 1, 10, 1, 10, 0, time.UTC),
+			time.Date(2024, 3, 1, 10, 5, 0, 0, time.UTC),
+		}),
+		data.NewField("message", nil, []string{"GET /", "POST /pay failed", "GET /cart", "late"}),
+		data.NewField("level", nil, []string{"INFO", "ERROR", "", "INFO"}),
+	)
+	frame.Meta = &data.FrameMeta{ExecutedQueryString: "SELECT * FROM access_logs"}
+	q := &sqlds.Query{
+		RefID:    "A",
+		Format:   sqlds.FormatOptionLogs,
+		Interval: time.Minute,
+		TimeRange: backend.TimeRange{
+			From: time.Date(2024, 3, 1, 10, 0, 20, 0, time.UTC),
+			To:   time.Date(2024, 3, 1, 10, 2, 0, 0, time.UTC),
+		},
+	}
+	frames, err := formatFrames(data.Frames{frame}, q, &QueryModel{LogsVolume: true})
+	assert.NilError(t, err)
+	assert.Equal(t, 3, len(frames))
+
+	// lines outside the time range are not counted
+	buckets := []time.Time{
+		time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 10, 1, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 10, 2, 0, 0, time.UTC),
+	}
+	for i, want := range []struct {
+		level  string
+		counts []int64
+	}{
+		{level: "error", counts: []int64{1, 0, 0}},
+		{level: "info", counts: []int64{1, 0, 0}},
+		{level: "unknown", counts: []int64{0, 1, 0}},
+	} {
+		volume := frames[i]
+		assert.Equal(t, "Limited", volume.Meta.Custom.(map[string]interface{})["logsVolumeType"])
+		assert.DeepEqual(t, data.Labels{"level": want.level}, volume.Fields[1].Labels)
+		for j, ts := range buckets {
+			assert.Equal(t, ts, volume.Fields[0].At(j))
+			assert.Equal(t, want.counts[j], volume.Fields[1].At(j))
+		}
+	}
+}
+
+func TestVolumeInterval(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		description string
+		interval    time.Duration
+		span        time.Duration
+		points      int64
+		want        time.Duration
+	}{
+		{description: "should use the query interval", interval: time.Minute, span: time.Hour, want: time.Minute},
+		{description: "should default to a second", want: time.Second},
+		{description: "should cap the number of buckets", interval: time.Second, span: 30 * 24 * time.Hour, want: 30 * 24 * time.Hour / maxVolumeBuckets},
+		{description: "should cap the buckets without an interval", span: 365 * 24 * time.Hour, want: 365 * 24 * time.Hour / maxVolumeBuckets},
+		{description: "should keep to the max data points", interval: time.Second, span: time.Hour, points: 60, want: time.Minute},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			q := &sqlds.Query{
+				Interval:      tc.interval,
+				MaxDataPoints: tc.points,
+				TimeRange:     backend.TimeRange{From: from, To: from.Add(tc.span)},
+			}
+			assert.Equal(t, tc.want, volumeInterval(q))
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package maxcompute
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tableschema"
+)
+
+// metadataTTL is how long table metadata is cached. It is short so new
+// partitions show up quickly while dashboards refreshing many panels share lookups.
+const metadataTTL = time.Minute
+
+var _ macros.Metadata = (*Metadata)(nil)
+
+type cacheEntry[T any] struct {
+	value   T
+	expires time.Time
+}
+
+// ttlCache caches values for a fixed duration.
+type ttlCache[T any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cacheEntry[T]
+	now     func() time.Time
+}
+
+func newTTLCache[T any](ttl time.Duration) *ttlCache[T] {
+	return &ttlCache[T]{ttl: ttl, entries: map[string]cacheEntry[T]{}, now: time.Now}
+}
+
+// get returns the cached value for key, calling load when it is missing or expired.
+// Errors are not cached.
+func (c *ttlCache[T]) get(key string, load func() (T, error)) (T, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(e.expires) {
+		return e.value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cacheEntry[T]{value: value, expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return value, nil
+}
+
+// Metadata looks up table metadata through the ODPS API and caches it for metadataTTL.
+type Metadata struct {
+	project    string
+	schemas    *ttlCache[*tableschema.TableSchema]
+	partitions *ttlCache[[]map[string]string]
+
+	loadSchema     func(project, table string) (*tableschema.TableSchema, error)
+	loadPartitions func(project, table string) ([]string, error)
+}
+
+// NewMetadata creates a Metadata reading from the project configured in config.
+func NewMetadata(config *odps.Config) *Metadata {
+	ins := config.GenOdps()
+	return &Metadata{
+		project:    config.ProjectName,
+		schemas:    newTTLCache[*tableschema.TableSchema](metadataTTL),
+		partitions: newTTLCache[[]map[string]string](metadataTTL),
+		loadSchema: func(project, table string) (*tableschema.TableSchema, error) {
+			t := odps.NewTable(ins, project, "", table)
+			if err := t.Load(); err != nil {
+				return nil, err
+			}
+			schema := t.Schema()
+			return &schema, nil
+		},
+		loadPartitions: func(project, table string) ([]string, error) {
+			t := odps.NewTable(ins, project, "", table)
+			partitions, err := t.GetPartitions()
+			if err != nil {
+				return nil, err
+			}
+			names := make([]string, len(partitions))
+			for i, p := range partitions {
+				names[i] = p.Spec()
+			}
+			return names, nil
+		},
+	}
+}
+
+// splitTable splits "project.table" into its parts, defaulting to the datasource project.
+func (m *Metadata) splitTable(table string) (string, string) {
+	if project, name, ok := strings.Cut(table, "."); ok {
+		return project, name
+	}
+	return m.project, table
+}
+
+// Schema returns the schema of a table.
+func (m *Metadata) Schema(table string) (*tableschema.TableSchema, error) {
+	project, name := m.splitTable(table)
+	return m.schemas.get(project+"."+name, func() (*tableschema.TableSchema, error) {
+		return m.loadSchema(project, name)
+	})
+}
+
+// PartitionColumns returns the partition columns of a table in declaration order.
+func (m *Metadata) PartitionColumns(table string) ([]string, error) {
+	schema, err := m.Schema(table)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(schema.PartitionColumns))
+	for i, c := range schema.PartitionColumns {
+		columns[i] = c.Name
+	}
+	return columns, nil
+}
+
+// Partitions returns every partition of a table as partition column to value.
+func (m *Metadata) Partitions(table string) ([]map[string]string, error) {
+	project, name := m.splitTable(table)
+	return m.partitions.get(project+"."+name, func() ([]map[string]string, error) {
+		names, err := m.loadPartitions(project, name)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]map[string]string, len(names))
+		for i, n := range names {
+			res[i] = parsePartitionName(n)
+		}
+		return res, nil
+	})
+}
+
+// parsePartitionName parses a partition name as rendered by odps.Partition.Spec,
+// such as "ds='20060102',hh='15'".
+func parsePartitionName(name string) map[string]string {
+	res := map[string]string{}
+	for len(name) > 0 {
+		key, rest, ok := strings.Cut(name, "=")
+		if !ok {
+			break
+		}
+		key = strings.TrimSpace(key)
+		rest = strings.TrimSpace(rest)
+
+		value := rest
+		if strings.HasPrefix(rest, "'") {
+			end := strings.Index(rest[1:], "'")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			value, rest = rest[1:end+1], rest[min(end+2, len(rest)):]
+		} else if i := strings.Index(rest, ","); i >= 0 {
+			value, rest = rest[:i], rest[i:]
+		} else {
+			rest = ""
+		}
+		res[key] = strings.TrimSpace(value)
+		name = strings.TrimLeft(rest, ", ")
+	}
+	return res
+}
@@ -0,0 +1,70 @@
+package maxcompute
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tableschema"
+	"gotest.tools/assert"
+)
+
+func newTestMetadata(partitions map[string][]string, columns map[string][]string) (*Metadata, *int) {
+	calls := 0
+	return &Metadata{
+		project:    "default_project",
+		schemas:    newTTLCache[*tableschema.TableSchema](metadataTTL),
+		partitions: newTTLCache[[]map[string]string](metadataTTL),
+		loadSchema: func(project, table string) (*tableschema.TableSchema, error) {
+			cols, ok := columns[project+"."+table]
+			if !ok {
+				return nil, errors.New("table not found")
+			}
+			schema := &tableschema.TableSchema{TableName: table}
+			for _, c := range cols {
+				schema.PartitionColumns = append(schema.PartitionColumns, tableschema.Column{Name: c})
+			}
+			return schema, nil
+		},
+		loadPartitions: func(project, table string) ([]string, error) {
+			calls++
+			return partitions[project+"."+table], nil
+		},
+	}, &calls
+}
+
+func TestParsePartitionName(t *testing.T) {
+	assert.DeepEqual(t, map[string]string{"ds": "20240101"}, parsePartitionName("ds='20240101'"))
+	assert.DeepEqual(t, map[string]string{"ds": "20240101", "hh": "09"}, parsePartitionName("ds='20240101', hh='09'"))
+	assert.DeepEqual(t, map[string]string{"region": "a, b", "ds": "1"}, parsePartitionName("region='a, b', ds='1'"))
+	assert.DeepEqual(t, map[string]string{"ds": "1", "hh": "2"}, parsePartitionName("ds=1, hh=2"))
+}
+
+func TestMetadataCache(t *testing.T) {
+	m, calls := newTestMetadata(
+		map[string][]string{"default_project.events": {"ds='20240101'", "ds='20240102'"}},
+		map[string][]string{"default_project.events": {"ds"}},
+	)
+	now := time.Now()
+	m.partitions.now = func() time.Time { return now }
+
+	partitions, err := m.Partitions("events")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []map[string]string{{"ds": "20240101"}, {"ds": "20240102"}}, partitions)
+
+	_, err = m.Partitions("default_project.events")
+	assert.NilError(t, err)
+	assert.Equal(t, 1, *calls)
+
+	now = now.Add(metadataTTL + time.Second)
+	_, err = m.Partitions("events")
+	assert.NilError(t, err)
+	assert.Equal(t, 2, *calls)
+
+	columns, err := m.PartitionColumns("events")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"ds"}, columns)
+
+	_, err = m.PartitionColumns("other_project.events")
+	assert.ErrorContains(t, err, "table not found")
+}
@@ -0,0 +1,200 @@
+package maxcompute
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/converters"
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+)
+
+// QueryModel holds the MaxCompute specific options of a query, on top of the
+// ones sqlds reads itself.
+type QueryModel struct {
+	RawSQL string `json:"rawSql"`
+	// QueryType is annotation for the annotation queries of a dashboard.
+	QueryType string `json:"queryType,omitempty"`
+	// Timezone overrides the datasource timezone for this query.
+	Timezone string `json:"timezone,omitempty"`
+	// Variables are the raw values of the template variables used by $__in and $__values.
+	Variables map[string]macros.Variable `json:"variables,omitempty"`
+	// TimeShift moves the time values of the result forward, so a series queried
+	// with $__timeFilterShift overlays the current one.
+	TimeShift string `json:"timeShift,omitempty"`
+	// FlattenStructs expands STRUCT columns into one field per nested field.
+	FlattenStructs bool `json:"flattenStructs,omitempty"`
+	// Explode names an ARRAY or MAP column to expand into one row per element.
+	Explode string `json:"explode,omitempty"`
+	// DecimalMode overrides the datasource DECIMAL mode for the query.
+	DecimalMode converters.DecimalMode `json:"decimalMode,omitempty"`
+	// IntervalMode overrides the datasource INTERVAL mode for the query.
+	IntervalMode converters.IntervalMode `json:"intervalMode,omitempty"`
+	// BinaryMode selects how the BINARY values of the query are returned.
+	BinaryMode converters.BinaryMode `json:"binaryMode,omitempty"`
+	// ColumnComments fills the display name, description and unit of the
+	// fields from the column comments of the table a single table select reads.
+	ColumnComments bool `json:"columnComments,omitempty"`
+	// Logs maps the time, body and level columns of a query in the logs format.
+	Logs LogsOptions `json:"logs,omitempty"`
+	// LogsVolume returns the number of log lines per level over time instead
+	// of the lines, for the Explore logs volume.
+	LogsVolume bool `json:"logsVolume,omitempty"`
+	// Trace maps the span columns of a query in the trace format.
+	Trace TraceOptions `json:"trace,omitempty"`
+	// TraceID is the trace a query in the trace format is limited to, and the
+	// value of $__traceId.
+	TraceID string `json:"traceId,omitempty"`
+	// SplitBy returns one frame per distinct combination of the values of these
+	// columns, with the values as field labels.
+	SplitBy []string `json:"splitBy,omitempty"`
+	// MaxSeries overrides the datasource maximum number of series of a split query.
+	MaxSeries int `json:"maxSeries,omitempty"`
+	// Annotations maps the columns of an annotation query.
+	Annotations AnnotationOptions `json:"annotations,omitempty"`
+}
+
+func GetQueryModel(query backend.DataQuery) (*QueryModel, error) {
+	model := &QueryModel{}
+	if err := json.Unmarshal(query.JSON, model); err != nil {
+		return nil, sqlds.PluginError(fmt.Errorf("%w: %v", sqlds.ErrorJSON, err))
+	}
+
+	return model, nil
+}
+
+// macroOptions merges the query level options over the datasource ones.
+func (d *MaxComputeDriver) macroOptions(model *QueryModel) (macros.Options, error) {
+	opts := d.datasourceMacroOptions()
+	opts.Variables = model.Variables
+	opts.TraceID = model.TraceID
+	if model.Timezone != "" {
+		loc, err := time.LoadLocation(model.Timezone)
+		if err != nil {
+			return opts, sqlds.DownstreamError(fmt.Errorf("%s: %w", model.Timezone, ErrorMessageInvalidTimezone))
+		}
+		opts.Location = loc
+	}
+
+	return opts, nil
+}
+
+// interpolate expands the macros of a query with its own options and writes the
+// result back into the query JSON, leaving nothing for sqlds to expand.
+func (d *MaxComputeDriver) interpolate(query backend.DataQuery) (backend.DataQuery, error) {
+	model, err := GetQueryModel(query)
+	if err != nil {
+		return query, err
+	}
+
+	opts, err := d.macroOptions(model)
+	if err != nil {
+		return query, err
+	}
+
+	if model.TimeShift != "" {
+		if _, err := macros.ParseOffset(model.TimeShift); err != nil {
+			return query, err
+		}
+	}
+
+	if err := converters.ValidateDecimalMode(model.DecimalMode); err != nil {
+		return query, err
+	}
+
+	if err := converters.ValidateIntervalMode(model.IntervalMode); err != nil {
+		return query, err
+	}
+
+	if err := converters.ValidateBinaryMode(model.BinaryMode); err != nil {
+		return query, err
+	}
+
+	if err := model.Trace.validate(); err != nil {
+		return query, err
+	}
+
+	q, err := sqlds.GetQuery(query, nil, false)
+	if err != nil {
+		return query, err
+	}
+
+	rawSQL, err := macros.Interpolate(q, d.macros(opts))
+	if err != nil {
+		return query, fmt.Errorf("%s: %w", "Could not apply macros", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(query.JSON, &fields); err != nil {
+		return query, sqlds.PluginError(fmt.Errorf("%w: %v", sqlds.ErrorJSON, err))
+	}
+	if fields["rawSql"], err = json.Marshal(rawSQL); err != nil {
+		return query, sqlds.PluginError(err)
+	}
+	if query.JSON, err = json.Marshal(fields); err != nil {
+		return query, sqlds.PluginError(err)
+	}
+
+	return query, nil
+}
+
+// converterOptions merges the query level conversion options over the datasource ones.
+func (d *MaxComputeDriver) converterOptions(model *QueryModel) converters.Options {
+	opts := d.settings.ConverterOptions()
+	opts.FlattenStructs = model.FlattenStructs
+	opts.Explode = model.Explode
+	opts.BinaryMode = model.BinaryMode
+	if model.DecimalMode != "" {
+		opts.DecimalMode = model.DecimalMode
+	}
+	if model.IntervalMode != "" {
+		opts.IntervalMode = model.IntervalMode
+	}
+	return opts
+}
+
+// queryFrames runs the query and converts the rows with the converters of the query.
+func (d *MaxComputeDriver) queryFrames(ctx context.Context, db *sql.DB, q *sqlds.Query, model *QueryModel, args ...interface{}) (data.Frames, error) {
+	if d.timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	rows, err := db.QueryContext(ctx, q.RawSQL, args...)
+	if err != nil {
+		errType := sqlds.ErrorQuery
+		if errors.Is(err, context.Canceled) {
+			errType = context.Canceled
+		}
+		return errorFrames(q), sqlds.DownstreamError(fmt.Errorf("%w: %s", errType, err.Error()))
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			backend.Logger.Error(err.Error())
+		}
+	}()
+
+	frame, err := converters.FrameFromRows(rows, d.converterOptions(model))
+	if err != nil {
+		return errorFrames(q), sqlds.PluginError(fmt.Errorf("%w: %s", err, "Could not process SQL results"))
+	}
+	frame.Name = q.RefID
+	if frame.Meta == nil {
+		frame.Meta = &data.FrameMeta{}
+	}
+	frame.Meta.ExecutedQueryString = q.RawSQL
+
+	frames, err := d.processFrames(model, data.Frames{frame})
+	if err != nil {
+		return errorFrames(q), err
+	}
+
+	return formatFrames(frames, q, model)
+}
@@ -0,0 +1,127 @@
+package maxcompute
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/converters"
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"gotest.tools/assert"
+)
+
+func TestInterpolateQuery(t *testing.T) {
+	from, _ := time.Parse(time.RFC3339, "2014-11-12T11:45:26Z")
+	to, _ := time.Parse(time.RFC3339, "2015-11-12T11:45:26Z")
+	driver := &MaxComputeDriver{settings: &Settings{
+		Timezone: "Asia/Shanghai",
+		Macros:   []macros.CustomMacro{{Name: "recent", Params: []string{"col"}, Body: "$__timeFrom(${col}, DATETIME)"}},
+	}}
+
+	tests := []struct {
+		description string
+		json        string
+		want        string
+		wantErr     error
+	}{
+		{
+			description: "should use the datasource timezone",
+			json:        `{"rawSql": "select * from foo where $__timeFilter(time, DATETIME)", "format": 1}`,
+			want:        "select * from foo where time >= DATETIME'2014-11-12 19:45:26' AND time <= DATETIME'2015-11-12 19:45:26'",
+		},
+		{
+			description: "should let the query timezone take precedence",
+			json:        `{"rawSql": "select * from foo where $__timeFrom(time, DATETIME)", "timezone": "UTC"}`,
+			want:        "select * from foo where time >= DATETIME'2014-11-12 11:45:26'",
+		},
+		{
+			description: "should expand custom macros",
+			json:        `{"rawSql": "select * from foo where $__recent(time)"}`,
+			want:        "select * from foo where time >= DATETIME'2014-11-12 19:45:26'",
+		},
+		{
+			description: "should expand the macros of annotation queries",
+			json:        `{"rawSql": "select * from deployments where $__timeFilter(time, DATETIME)", "queryType": "annotation"}`,
+			want:        "select * from deployments where time >= DATETIME'2014-11-12 19:45:26' AND time <= DATETIME'2015-11-12 19:45:26'",
+		},
+		{
+			description: "should quote the raw variable values",
+			json:        `{"rawSql": "select * from foo where $__in(region, $region)", "variables": {"region": {"values": ["cn-hangzhou", "it's"]}}}`,
+			want:        `select * from foo where region IN ('cn-hangzhou', 'it\'s')`,
+		},
+		{
+			description: "should keep the sqlds table and column macros",
+			json:        `{"rawSql": "select $__column from $__table", "table": "orders", "column": "amount"}`,
+			want:        "select amount from orders",
+		},
+		{
+			description: "should capture unknown variables",
+			json:        `{"rawSql": "select $__values($region)"}`,
+			wantErr:     macros.ErrorUnknownVariable,
+		},
+		{
+			description: "should capture invalid time shift",
+			json:        `{"rawSql": "select 1", "timeShift": "last week"}`,
+			wantErr:     macros.ErrorInvalidOffset,
+		},
+		{
+			description: "should capture invalid decimal mode",
+			json:        `{"rawSql": "select 1", "decimalMode": "double"}`,
+			wantErr:     converters.ErrorInvalidDecimalMode,
+		},
+		{
+			description: "should capture invalid interval mode",
+			json:        `{"rawSql": "select 1", "intervalMode": "seconds"}`,
+			wantErr:     converters.ErrorInvalidIntervalMode,
+		},
+		{
+			description: "should capture invalid binary mode",
+			json:        `{"rawSql": "select 1", "binaryMode": "octal"}`,
+			wantErr:     converters.ErrorInvalidBinaryMode,
+		},
+		{
+			description: "should capture invalid query timezone",
+			json:        `{"rawSql": "select 1", "timezone": "Mars/Olympus"}`,
+			wantErr:     ErrorMessageInvalidTimezone,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			query, err := driver.interpolate(backend.DataQuery{
+				RefID:     "A",
+				JSON:      json.RawMessage(tc.json),
+				TimeRange: backend.TimeRange{From: from, To: to},
+			})
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("%s not captured. %v", tc.wantErr, err)
+				}
+				return
+			}
+			assert.NilError(t, err)
+			model, err := GetQueryModel(query)
+			assert.NilError(t, err)
+			assert.Equal(t, tc.want, model.RawSQL)
+		})
+	}
+}
+
+func TestConverterOptions(t *testing.T) {
+	driver := &MaxComputeDriver{settings: &Settings{DecimalMode: converters.DecimalFloat}}
+
+	opts := driver.converterOptions(&QueryModel{})
+	assert.Equal(t, converters.DecimalFloat, opts.DecimalMode)
+
+	opts = driver.converterOptions(&QueryModel{DecimalMode: converters.DecimalScaled, Explode: "tags"})
+	assert.Equal(t, converters.DecimalScaled, opts.DecimalMode)
+	assert.Equal(t, "tags", opts.Explode)
+	assert.Equal(t, converters.IntervalMode(""), opts.IntervalMode)
+
+	opts = driver.converterOptions(&QueryModel{IntervalMode: converters.IntervalNumeric})
+	assert.Equal(t, converters.IntervalNumeric, opts.IntervalMode)
+
+	opts = driver.converterOptions(&QueryModel{BinaryMode: converters.BinaryHex})
+	assert.Equal(t, converters.BinaryHex, opts.BinaryMode)
+}
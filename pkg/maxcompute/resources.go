@@ -0,0 +1,25 @@
+package maxcompute
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// routes returns the resource endpoints served next to the sqlds completion ones.
+func (d *MaxComputeDriver) routes() map[string]func(http.ResponseWriter, *http.Request) {
+	return map[string]func(http.ResponseWriter, *http.Request){
+		"/macros": d.handleMacros,
+	}
+}
+
+// handleMacros serves the macro registry so the SQL editor can offer
+// completions and hover help.
+func (d *MaxComputeDriver) handleMacros(rw http.ResponseWriter, _ *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(d.settings.MacroOptions().Registry()); err != nil {
+		log.DefaultLogger.Error("could not encode macros", "error", err)
+		rw.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,32 @@
+package maxcompute
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
+	"gotest.tools/assert"
+)
+
+func TestHandleMacros(t *testing.T) {
+	driver := &MaxComputeDriver{settings: &Settings{Macros: []macros.CustomMacro{{Name: "region", Params: []string{"col"}, Body: "${col} = 'id'"}}}}
+	rec := httptest.NewRecorder()
+	driver.routes()["/macros"](rec, httptest.NewRequest(http.MethodGet, "/macros", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var got []macros.Macro
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, len(macros.Registry)+1, len(got))
+	assert.Equal(t, "region", got[len(got)-1].Name)
+	assert.Equal(t, "$__region(col)", got[len(got)-1].Example)
+	for i, m := range got[:len(macros.Registry)] {
+		assert.Equal(t, macros.Registry[i].Name, m.Name)
+		assert.Equal(t, macros.Registry[i].MinArgs, m.MinArgs)
+		assert.Equal(t, macros.Registry[i].MaxArgs, m.MaxArgs)
+		assert.Equal(t, macros.Registry[i].Example, m.Example)
+	}
+}
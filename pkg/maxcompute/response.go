@@ -0,0 +1,170 @@
+package maxcompute
+
+import (
+	"time"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+)
+
+// processFrames applies the query options that act on the converted frames
+// rather than on the SQL.
+func (d *MaxComputeDriver) processFrames(model *QueryModel, frames data.Frames) (data.Frames, error) {
+	if model.TimeShift != "" {
+		offset, err := macros.ParseOffset(model.TimeShift)
+		if err != nil {
+			return nil, err
+		}
+		shiftFrames(frames, offset)
+	}
+
+	if model.ColumnComments {
+		for _, frame := range frames {
+			d.describeFrame(frame)
+		}
+	}
+
+	if len(model.SplitBy) > 0 {
+		maxSeries := d.settings.maxSeries()
+		if model.MaxSeries > 0 {
+			maxSeries = model.MaxSeries
+		}
+		split := make(data.Frames, 0, len(frames))
+		for _, frame := range frames {
+			series, err := splitFrame(frame, model.SplitBy, maxSeries)
+			if err != nil {
+				return nil, err
+			}
+			split = append(split, series...)
+		}
+		frames = split
+	}
+
+	return frames, nil
+}
+
+// formatFrames prepares the frames for the requested format the way sqlds does,
+// converting long time series into wide ones with the fill mode of the query
+// and results into log lines or spans.
+// The format of each frame of an auto format query is inferred from its columns,
+// and annotation queries return annotations whatever their format.
+func formatFrames(frames data.Frames, q *sqlds.Query, model *QueryModel) (data.Frames, error) {
+	fill := fillMode
+	if q.FillMissing != nil {
+		fill = q.FillMissing
+	}
+
+	res := make(data.Frames, 0, len(frames))
+	for _, frame := range frames {
+		if frame.Meta == nil {
+			frame.Meta = &data.FrameMeta{}
+		}
+
+		if model.QueryType == QueryTypeAnnotation {
+			annotations, err := annotationsFrame(frame, model.Annotations)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, annotations)
+			continue
+		}
+
+		format := q.Format
+		if format == formatOptionAuto {
+			var reason string
+			format, reason = inferFormat(frame)
+			recordFormat(frame, format, reason)
+		}
+
+		switch format {
+		case sqlds.FormatOptionTable:
+			frame.Meta.PreferredVisualization = data.VisTypeTable
+		case sqlds.FormatOptionLogs:
+			logs, err := logsFrame(frame, model.Logs)
+			if err != nil {
+				return nil, err
+			}
+			if model.LogsVolume {
+				res = append(res, logsVolumeFrames(logs, q)...)
+				continue
+			}
+			frame = logs
+		case sqlds.FormatOptionTrace:
+			trace, err := traceFrame(frame, model.Trace, model.TraceID)
+			if err != nil {
+				return nil, err
+			}
+			frame = trace
+		default:
+			frame.Meta.PreferredVisualization = data.VisTypeGraph
+			count, err := frame.RowLen()
+			if err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				continue
+			}
+			if frame.TimeSeriesSchema().Type == data.TimeSeriesTypeLong {
+				wide, err := data.LongToWide(frame, fill)
+				if err != nil {
+					return nil, err
+				}
+				wide.Meta = frame.Meta
+				keepLabels(frame, wide)
+				frame = wide
+			}
+		}
+		res = append(res, frame)
+	}
+
+	return res, nil
+}
+
+// keepLabels copies the labels of the fields of a long frame, such as the ones
+// of a split query, onto the wide fields they were converted into.
+func keepLabels(long, wide *data.Frame) {
+	for _, f := range wide.Fields {
+		source, _ := long.FieldByName(f.Name)
+		if source == nil || len(source.Labels) == 0 {
+			continue
+		}
+		if f.Labels == nil {
+			f.Labels = data.Labels{}
+		}
+		for k, v := range source.Labels {
+			if _, ok := f.Labels[k]; !ok {
+				f.Labels[k] = v
+			}
+		}
+	}
+}
+
+// errorFrames carries the executed query to the query inspector when a query fails.
+func errorFrames(q *sqlds.Query) data.Frames {
+	frame := data.NewFrame(q.RefID)
+	frame.Meta = &data.FrameMeta{ExecutedQueryString: q.RawSQL}
+	return data.Frames{frame}
+}
+
+// shiftFrames moves every time value forward by the offset, so a series queried
+// with a shifted time range overlays the current one.
+func shiftFrames(frames data.Frames, offset macros.Offset) {
+	for _, frame := range frames {
+		for _, field := range frame.Fields {
+			switch field.Type() {
+			case data.FieldTypeTime:
+				for i := 0; i < field.Len(); i++ {
+					field.Set(i, offset.After(field.At(i).(time.Time)))
+				}
+			case data.FieldTypeNullableTime:
+				for i := 0; i < field.Len(); i++ {
+					if v := field.At(i).(*time.Time); v != nil {
+						t := offset.After(*v)
+						field.Set(i, &t)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package maxcompute
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+	"gotest.tools/assert"
+)
+
+func TestProcessFramesTimeShift(t *testing.T) {
+	t1 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	t2 := time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)
+	frame := data.NewFrame("A",
+		data.NewField("time", nil, []time.Time{t1, t2}),
+		data.NewField("end", nil, []*time.Time{&t2, nil}),
+		data.NewField("value", nil, []int64{1, 2}),
+	)
+
+	driver := &MaxComputeDriver{}
+	_, err := driver.processFrames(&QueryModel{TimeShift: "7d"}, data.Frames{frame})
+	assert.NilError(t, err)
+
+	assert.Equal(t, t1.AddDate(0, 0, 7), frame.Fields[0].At(0))
+	assert.Equal(t, t2.AddDate(0, 0, 7), frame.Fields[0].At(1))
+	assert.Equal(t, t2.AddDate(0, 0, 7), *frame.Fields[1].At(0).(*time.Time))
+	assert.Assert(t, frame.Fields[1].At(1).(*time.Time) == nil)
+	assert.Equal(t, int64(1), frame.Fields[2].At(0))
+
+	unchanged := data.NewFrame("B", data.NewField("time", nil, []time.Time{t1}))
+	_, err = driver.processFrames(&QueryModel{}, data.Frames{unchanged})
+	assert.NilError(t, err)
+	assert.Equal(t, t1, unchanged.Fields[0].At(0))
+}
+
+func TestFormatFramesFillMode(t *testing.T) {
+	t1 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Minute)
+	long := func() data.Frames {
+		return data.Frames{data.NewFrame("A",
+			data.NewField("time", nil, []time.Time{t1, t1, t2}),
+			data.NewField("region", nil, []string{"a", "b", "a"}),
+			data.NewField("orders", nil, []int64{1, 2, 3}),
+		)}
+	}
+
+	q := &sqlds.Query{RefID: "A", Format: sqlds.FormatOptionTimeSeries}
+	frames, err := formatFrames(long(), q, &QueryModel{})
+	assert.NilError(t, err)
+	assert.Assert(t, frames[0].Fields[2].At(1).(*int64) == nil)
+
+	// the fill mode of the query overrides the datasource one
+	q.FillMissing = &data.FillMissing{Mode: data.FillModeValue, Value: 0}
+	frames, err = formatFrames(long(), q, &QueryModel{})
+	assert.NilError(t, err)
+	assert.Equal(t, int64(0), frames[0].Fields[2].At(1))
+}
@@ -5,10 +5,85 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/converters"
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
 	"github.com/aliyun/aliyun-odps-go-sdk/odps"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 )
 
+// Settings holds the plugin options that are not part of the ODPS connection config.
+type Settings struct {
+	// Timezone is the IANA name of the project timezone, used to render time
+	// literals. When empty, the timezone set in the project properties is used.
+	Timezone string `json:"timezone"`
+	// Macros are the admin defined macros registered next to the built-in ones.
+	Macros []macros.CustomMacro `json:"macros"`
+	// NestedTypesAsString renders ARRAY, MAP and STRUCT values as ODPS literal
+	// strings instead of JSON, for dashboards built on the old rendering.
+	NestedTypesAsString bool `json:"nestedTypesAsString"`
+	// DecimalMode selects how DECIMAL values are returned: string, float or scaled.
+	DecimalMode converters.DecimalMode `json:"decimalMode"`
+	// IntervalMode selects how INTERVAL values are returned: string or numeric.
+	IntervalMode converters.IntervalMode `json:"intervalMode"`
+	// TimestampNTZTimezone is the IANA name of the timezone TIMESTAMP_NTZ wall
+	// clocks are read in, UTC when empty.
+	TimestampNTZTimezone string `json:"timestampNtzTimezone"`
+	// MaxSeries is the number of frames a query split by columns returns at
+	// most, 100 when unset.
+	MaxSeries int `json:"maxSeries"`
+}
+
+// Location returns the configured timezone, UTC when none is set.
+func (s *Settings) Location() *time.Location {
+	if s == nil || s.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// MacroOptions returns the datasource level macro options.
+func (s *Settings) MacroOptions() macros.Options {
+	opts := macros.Options{Location: s.Location()}
+	if s != nil {
+		opts.Custom = s.Macros
+	}
+	return opts
+}
+
+// ConverterOptions returns the datasource level options of the type converters.
+func (s *Settings) ConverterOptions() converters.Options {
+	if s == nil {
+		return converters.Options{}
+	}
+	return converters.Options{
+		NestedAsString: s.NestedTypesAsString,
+		DecimalMode:    s.DecimalMode,
+		IntervalMode:   s.IntervalMode,
+		NTZLocation:    s.ntzLocation(),
+	}
+}
+
+// ntzLocation returns the TIMESTAMP_NTZ timezone, UTC when none is set.
+func (s *Settings) ntzLocation() *time.Location {
+	loc, err := time.LoadLocation(s.TimestampNTZTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// maxSeries returns the maximum number of series of a split query.
+func (s *Settings) maxSeries() int {
+	if s == nil || s.MaxSeries <= 0 {
+		return defaultMaxSeries
+	}
+	return s.MaxSeries
+}
+
 type CustomOption struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
@@ -96,3 +171,32 @@ func LoadMaxComputeConfig(settings backend.DataSourceInstanceSettings) (*odps.Co
 
 	return config, isValid(config)
 }
+
+func LoadSettings(settings backend.DataSourceInstanceSettings) (*Settings, error) {
+	res := &Settings{}
+	if err := json.Unmarshal(settings.JSONData, res); err != nil {
+		return nil, fmt.Errorf("%s: %w", err.Error(), ErrorMessageInvalidJSON)
+	}
+
+	if _, err := time.LoadLocation(res.Timezone); err != nil {
+		return nil, fmt.Errorf("%s: %w", res.Timezone, ErrorMessageInvalidTimezone)
+	}
+
+	if _, err := time.LoadLocation(res.TimestampNTZTimezone); err != nil {
+		return nil, fmt.Errorf("%s: %w", res.TimestampNTZTimezone, ErrorMessageInvalidTimezone)
+	}
+
+	if err := macros.ValidateCustomMacros(res.Macros); err != nil {
+		return nil, err
+	}
+
+	if err := converters.ValidateDecimalMode(res.DecimalMode); err != nil {
+		return nil, err
+	}
+
+	if err := converters.ValidateIntervalMode(res.IntervalMode); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
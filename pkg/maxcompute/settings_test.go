@@ -7,6 +7,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/converters"
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/macros"
 	"github.com/aliyun/aliyun-odps-go-sdk/odps"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"gotest.tools/assert"
@@ -86,3 +88,65 @@ func TestLoadSettings(t *testing.T) {
 		}
 	})
 }
+
+func TestLoadPluginSettings(t *testing.T) {
+	tests := []struct {
+		description  string
+		jsonData     string
+		wantTimezone string
+		wantLocation string
+		wantErr      error
+
+		wantNestedAsString bool
+		wantDecimalMode    converters.DecimalMode
+		wantIntervalMode   converters.IntervalMode
+		wantNTZLocation    string
+	}{
+		{description: "should default to UTC", jsonData: `{}`, wantLocation: "UTC"},
+		{description: "should parse the timezone", jsonData: `{ "timezone": "Asia/Shanghai" }`, wantTimezone: "Asia/Shanghai", wantLocation: "Asia/Shanghai"},
+		{description: "should capture invalid timezone", jsonData: `{ "timezone": "Mars/Olympus" }`, wantErr: ErrorMessageInvalidTimezone},
+		{description: "should capture invalid json", jsonData: `{ "timezone": `, wantErr: ErrorMessageInvalidJSON},
+		{description: "should parse custom macros", jsonData: `{ "macros": [{"name": "region", "params": ["col"], "body": "${col} = 'id'"}] }`, wantLocation: "UTC"},
+		{description: "should parse the nested types rendering", jsonData: `{ "nestedTypesAsString": true }`, wantLocation: "UTC", wantNestedAsString: true},
+		{description: "should parse the decimal mode", jsonData: `{ "decimalMode": "float" }`, wantLocation: "UTC", wantDecimalMode: converters.DecimalFloat},
+		{description: "should capture invalid decimal mode", jsonData: `{ "decimalMode": "double" }`, wantErr: converters.ErrorInvalidDecimalMode},
+		{description: "should parse the interval mode", jsonData: `{ "intervalMode": "numeric" }`, wantLocation: "UTC", wantIntervalMode: converters.IntervalNumeric},
+		{description: "should capture invalid interval mode", jsonData: `{ "intervalMode": "seconds" }`, wantErr: converters.ErrorInvalidIntervalMode},
+		{description: "should parse the TIMESTAMP_NTZ timezone", jsonData: `{ "timestampNtzTimezone": "Europe/Berlin" }`, wantLocation: "UTC", wantNTZLocation: "Europe/Berlin"},
+		{description: "should capture invalid TIMESTAMP_NTZ timezone", jsonData: `{ "timestampNtzTimezone": "Mars/Olympus" }`, wantErr: ErrorMessageInvalidTimezone},
+		{description: "should capture invalid custom macros", jsonData: `{ "macros": [{"name": "region", "params": [], "body": "${col} = 'id'"}] }`, wantErr: macros.ErrorInvalidCustomMacro},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("[%v/%v] %s", i+1, len(tests), tc.description), func(t *testing.T) {
+			s, err := LoadSettings(backend.DataSourceInstanceSettings{JSONData: []byte(tc.jsonData)})
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("%s not captured. %v", tc.wantErr, err)
+				}
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, tc.wantTimezone, s.Timezone)
+			assert.Equal(t, tc.wantLocation, s.Location().String())
+			assert.Equal(t, tc.wantNestedAsString, s.ConverterOptions().NestedAsString)
+			assert.Equal(t, tc.wantDecimalMode, s.ConverterOptions().DecimalMode)
+			assert.Equal(t, tc.wantIntervalMode, s.ConverterOptions().IntervalMode)
+			if tc.wantNTZLocation == "" {
+				tc.wantNTZLocation = "UTC"
+			}
+			assert.Equal(t, tc.wantNTZLocation, s.ConverterOptions().NTZLocation.String())
+		})
+	}
+}
+
+func TestDriverLocation(t *testing.T) {
+	// macros render in UTC unless the datasource sets a timezone
+	driver := &MaxComputeDriver{settings: &Settings{}, metadata: &Metadata{}}
+	assert.Equal(t, "UTC", driver.location().String())
+
+	driver.settings.Timezone = "Europe/Berlin"
+	assert.Equal(t, "Europe/Berlin", driver.location().String())
+
+	driver = &MaxComputeDriver{}
+	assert.Equal(t, "UTC", driver.location().String())
+}
@@ -0,0 +1,109 @@
+package maxcompute
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+)
+
+// defaultMaxSeries is the number of frames a split query returns when no
+// maximum is configured.
+const defaultMaxSeries = 100
+
+// series is the rows of a split frame sharing the values of the split columns.
+type series struct {
+	labels data.Labels
+	rows   []int
+}
+
+// splitFrame returns one frame per distinct combination of the values of the
+// columns, in order of first appearance, with the values as labels of the
+// other fields. Series past maxSeries are dropped with a warning notice.
+func splitFrame(frame *data.Frame, columns []string, maxSeries int) (data.Frames, error) {
+	split := map[int]bool{}
+	for _, name := range columns {
+		i := findField(frame, name, nil, nil)
+		if i < 0 {
+			return nil, sqlds.DownstreamError(fmt.Errorf("%w: %s", ErrorColumnNotFound, name))
+		}
+		split[i] = true
+	}
+
+	rows, err := frame.RowLen()
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*series
+	byKey := map[string]*series{}
+	for row := 0; row < rows; row++ {
+		labels := data.Labels{}
+		values := make([]string, 0, len(split))
+		for i, f := range frame.Fields {
+			if !split[i] {
+				continue
+			}
+			v, _ := labelValue(f, row)
+			labels[f.Name] = v
+			values = append(values, v)
+		}
+		key := strings.Join(values, "\x00")
+		s, ok := byKey[key]
+		if !ok {
+			s = &series{labels: labels}
+			byKey[key] = s
+			all = append(all, s)
+		}
+		s.rows = append(s.rows, row)
+	}
+	if len(all) == 0 {
+		return data.Frames{frame}, nil
+	}
+
+	var notice *data.Notice
+	if maxSeries > 0 && len(all) > maxSeries {
+		notice = &data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text: fmt.Sprintf("The query returned %d series, only the first %d are shown. Narrow the query or raise the maximum number of series",
+				len(all), maxSeries),
+		}
+		all = all[:maxSeries]
+	}
+
+	frames := make(data.Frames, 0, len(all))
+	for _, s := range all {
+		fields := make([]*data.Field, 0, len(frame.Fields)-len(split))
+		for i, f := range frame.Fields {
+			if split[i] {
+				continue
+			}
+			field := data.NewFieldFromFieldType(f.Type(), len(s.rows))
+			field.Name = f.Name
+			field.Config = f.Config
+			if !isTimeType(f.Type()) {
+				field.Labels = s.labels.Copy()
+				for k, v := range f.Labels {
+					field.Labels[k] = v
+				}
+			}
+			for j, row := range s.rows {
+				field.Set(j, f.At(row))
+			}
+			fields = append(fields, field)
+		}
+		out := data.NewFrame(frame.Name, fields...)
+		if frame.Meta != nil {
+			meta := *frame.Meta
+			meta.Notices = append([]data.Notice(nil), frame.Meta.Notices...)
+			out.Meta = &meta
+		}
+		frames = append(frames, out)
+	}
+
+	if notice != nil {
+		frames[0].AppendNotices(*notice)
+	}
+	return frames, nil
+}
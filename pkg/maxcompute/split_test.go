@@ -0,0 +1,114 @@
+package maxcompute
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+	"gotest.tools/assert"
+)
+
+func ordersFrame() *data.Frame {
+	t1 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Minute)
+	frame := data.NewFrame("A",
+		data.NewField("ts", nil, []time.Time{t1, t1, t1, t2, t2}),
+		data.NewField("region", nil, []string{"id", "sg", "id", "id", "sg"}),
+		data.NewField("channel", nil, []string{"app", "app", "web", "app", "app"}),
+		data.NewField("orders", nil, []int64{1, 2, 3, 4, 5}),
+	)
+	frame.Meta = &data.FrameMeta{ExecutedQueryString: "SELECT * FROM orders"}
+	return frame
+}
+
+func TestSplitFrame(t *testing.T) {
+	type series struct {
+		Labels data.Labels
+		Orders []int64
+	}
+	tests := []struct {
+		description string
+		columns     []string
+		maxSeries   int
+		want        []series
+		wantNotice  bool
+		wantErr     error
+	}{
+		{
+			description: "should split by every combination of the columns in order of appearance",
+			columns:     []string{"REGION", "channel"},
+			want: []series{
+				{Labels: data.Labels{"region": "id", "channel": "app"}, Orders: []int64{1, 4}},
+				{Labels: data.Labels{"region": "sg", "channel": "app"}, Orders: []int64{2, 5}},
+				{Labels: data.Labels{"region": "id", "channel": "web"}, Orders: []int64{3}},
+			},
+		},
+		{
+			description: "should drop the series past the maximum with a notice",
+			columns:     []string{"region", "channel"},
+			maxSeries:   2,
+			want: []series{
+				{Labels: data.Labels{"region": "id", "channel": "app"}, Orders: []int64{1, 4}},
+				{Labels: data.Labels{"region": "sg", "channel": "app"}, Orders: []int64{2, 5}},
+			},
+			wantNotice: true,
+		},
+		{
+			description: "should capture a missing column",
+			columns:     []string{"country"},
+			wantErr:     ErrorColumnNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			frames, err := splitFrame(ordersFrame(), tc.columns, tc.maxSeries)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("%s not captured. %v", tc.wantErr, err)
+				}
+				assert.Equal(t, backend.ErrorSourceDownstream, sqlds.ErrorSource(err))
+				return
+			}
+			assert.NilError(t, err)
+
+			var got []series
+			for _, frame := range frames {
+				assert.Equal(t, "A", frame.Name)
+				assert.Equal(t, "SELECT * FROM orders", frame.Meta.ExecutedQueryString)
+				assert.Equal(t, 2, len(frame.Fields))
+				assert.Assert(t, frame.Fields[0].Labels == nil)
+				s := series{Labels: frame.Fields[1].Labels}
+				for row := 0; row < frame.Rows(); row++ {
+					s.Orders = append(s.Orders, frame.Fields[1].At(row).(int64))
+				}
+				got = append(got, s)
+			}
+			assert.DeepEqual(t, tc.want, got)
+			assert.Equal(t, tc.wantNotice, len(frames[0].Meta.Notices) == 1)
+		})
+	}
+}
+
+func TestProcessFramesSplitBy(t *testing.T) {
+	driver := &MaxComputeDriver{settings: &Settings{MaxSeries: 1}}
+
+	frames, err := driver.processFrames(&QueryModel{SplitBy: []string{"region"}}, data.Frames{ordersFrame()})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(frames))
+	assert.Equal(t, 1, len(frames[0].Meta.Notices))
+
+	// the query maximum overrides the datasource one
+	frames, err = driver.processFrames(&QueryModel{SplitBy: []string{"region"}, MaxSeries: 5}, data.Frames{ordersFrame()})
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(frames))
+
+	// each series is a wide time series of its own
+	q := &sqlds.Query{RefID: "A", Format: sqlds.FormatOptionTimeSeries}
+	frames, err = formatFrames(frames, q, &QueryModel{})
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(frames))
+	assert.DeepEqual(t, data.Labels{"region": "id", "channel": "app"}, frames[0].Fields[1].Labels)
+}
@@ -0,0 +1,247 @@
+package maxcompute
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+)
+
+var ErrorInvalidTimeUnit = errors.New("invalid time unit. Expected s, ms, us or ns")
+
+// TraceOptions maps the columns of a query in the trace format. Empty columns
+// are detected by name, ignoring case and underscores.
+type TraceOptions struct {
+	TraceIDColumn       string `json:"traceIdColumn,omitempty"`
+	SpanIDColumn        string `json:"spanIdColumn,omitempty"`
+	ParentSpanIDColumn  string `json:"parentSpanIdColumn,omitempty"`
+	OperationNameColumn string `json:"operationNameColumn,omitempty"`
+	ServiceNameColumn   string `json:"serviceNameColumn,omitempty"`
+	StartTimeColumn     string `json:"startTimeColumn,omitempty"`
+	DurationColumn      string `json:"durationColumn,omitempty"`
+	// ServiceTagsColumn is the MAP column of the service tags.
+	ServiceTagsColumn string `json:"serviceTagsColumn,omitempty"`
+	// TagsColumns are the MAP columns merged into the span tags, every other
+	// MAP column when empty.
+	TagsColumns []string `json:"tagsColumns,omitempty"`
+	// TimeUnit is the unit of the duration and of numeric start times, ms by default.
+	TimeUnit string `json:"timeUnit,omitempty"`
+}
+
+// timeUnits are the accepted units of numeric span times.
+var timeUnits = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// validate accepts the known time units and the empty default.
+func (o TraceOptions) validate() error {
+	if _, ok := timeUnits[o.TimeUnit]; o.TimeUnit != "" && !ok {
+		return sqlds.DownstreamError(fmt.Errorf("%w: %s", ErrorInvalidTimeUnit, o.TimeUnit))
+	}
+	return nil
+}
+
+// columns returns the columns of the trace frame fields, detected by name.
+func (o TraceOptions) columns() []column {
+	return []column{
+		{field: "traceID", configured: o.TraceIDColumn, names: []string{"traceid"}, required: true},
+		{field: "spanID", configured: o.SpanIDColumn, names: []string{"spanid"}, required: true},
+		{field: "parentSpanID", configured: o.ParentSpanIDColumn, names: []string{"parentspanid", "parentid"}},
+		{field: "operationName", configured: o.OperationNameColumn, names: []string{"operationname", "spanname", "name"}},
+		{field: "serviceName", configured: o.ServiceNameColumn, names: []string{"servicename", "service"}},
+		{field: "startTime", configured: o.StartTimeColumn, names: []string{"starttime", "start", "timestamp"}, required: true},
+		{field: "duration", configured: o.DurationColumn, names: []string{"duration"}, required: true},
+		{field: "serviceTags", configured: o.ServiceTagsColumn, names: []string{"servicetags", "resource"}},
+	}
+}
+
+// keyValue is a span tag as the trace view reads it.
+type keyValue struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// mapTags returns the entries of a MAP value as tags sorted by key.
+func mapTags(f *data.Field, row int) ([]keyValue, error) {
+	v, ok := f.ConcreteAt(row)
+	if !ok {
+		return nil, nil
+	}
+	raw, ok := v.(json.RawMessage)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a MAP column", f.Name)
+	}
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("%s is not a MAP column", f.Name)
+	}
+	tags := make([]keyValue, 0, len(entries))
+	for k, v := range entries {
+		tags = append(tags, keyValue{Key: k, Value: v})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	return tags, nil
+}
+
+// isMapField reports whether a field holds JSON objects, as MAP columns do.
+func isMapField(f *data.Field) bool {
+	if f.Type() != data.FieldTypeJSON && f.Type() != data.FieldTypeNullableJSON {
+		return false
+	}
+	for row := 0; row < f.Len(); row++ {
+		if v, ok := f.ConcreteAt(row); ok {
+			raw := strings.TrimSpace(string(v.(json.RawMessage)))
+			return strings.HasPrefix(raw, "{")
+		}
+	}
+	return false
+}
+
+// spanMillis reads a span time in milliseconds, from a time or a number in unit.
+func spanMillis(f *data.Field, row int, unit time.Duration) (float64, error) {
+	v, ok := f.ConcreteAt(row)
+	if !ok {
+		return 0, fmt.Errorf("%s is null in row %d", f.Name, row)
+	}
+	if t, ok := v.(time.Time); ok {
+		return float64(t.UnixNano()) / float64(time.Millisecond), nil
+	}
+	n, err := f.FloatAt(row)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a time or a number", f.Name)
+	}
+	return n * float64(unit) / float64(time.Millisecond), nil
+}
+
+// traceFrame converts a result into a trace frame: one row per span with the
+// tags of the MAP columns. When traceID is set only its spans are kept.
+func traceFrame(frame *data.Frame, opts TraceOptions, traceID string) (*data.Frame, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	unit := time.Millisecond
+	if opts.TimeUnit != "" {
+		unit = timeUnits[opts.TimeUnit]
+	}
+
+	columns, err := resolveColumns(frame, opts.columns())
+	if err != nil {
+		return nil, err
+	}
+
+	var tagFields []*data.Field
+	if len(opts.TagsColumns) > 0 {
+		for _, name := range opts.TagsColumns {
+			i := findField(frame, name, nil, nil)
+			if i < 0 {
+				return nil, sqlds.DownstreamError(fmt.Errorf("%w: %s", ErrorColumnNotFound, name))
+			}
+			tagFields = append(tagFields, frame.Fields[i])
+		}
+	} else {
+		mapped := map[int]bool{}
+		for _, i := range columns {
+			mapped[i] = true
+		}
+		for i, f := range frame.Fields {
+			if !mapped[i] && isMapField(f) {
+				tagFields = append(tagFields, f)
+			}
+		}
+	}
+
+	rows, err := frame.RowLen()
+	if err != nil {
+		return nil, err
+	}
+
+	text := func(name string, row int) string {
+		i, ok := columns[name]
+		if !ok {
+			return ""
+		}
+		v, _ := labelValue(frame.Fields[i], row)
+		return v
+	}
+
+	trace := data.NewFrame(frame.Name,
+		data.NewField("traceID", nil, []string{}),
+		data.NewField("spanID", nil, []string{}),
+		data.NewField("parentSpanID", nil, []*string{}),
+		data.NewField("operationName", nil, []string{}),
+		data.NewField("serviceName", nil, []string{}),
+		data.NewField("serviceTags", nil, []json.RawMessage{}),
+		data.NewField("startTime", nil, []float64{}),
+		data.NewField("duration", nil, []float64{}),
+		data.NewField("tags", nil, []json.RawMessage{}),
+	)
+	for row := 0; row < rows; row++ {
+		id := text("traceID", row)
+		if traceID != "" && id != traceID {
+			continue
+		}
+
+		var parent *string
+		if p := text("parentSpanID", row); p != "" {
+			parent = &p
+		}
+
+		serviceTags := []keyValue{}
+		if i, ok := columns["serviceTags"]; ok {
+			if serviceTags, err = mapTags(frame.Fields[i], row); err != nil {
+				return nil, sqlds.DownstreamError(err)
+			}
+		}
+
+		start, err := spanMillis(frame.Fields[columns["startTime"]], row, unit)
+		if err != nil {
+			return nil, sqlds.DownstreamError(err)
+		}
+		duration, err := spanMillis(frame.Fields[columns["duration"]], row, unit)
+		if err != nil {
+			return nil, sqlds.DownstreamError(err)
+		}
+
+		tags := []keyValue{}
+		for _, f := range tagFields {
+			t, err := mapTags(f, row)
+			if err != nil {
+				return nil, sqlds.DownstreamError(err)
+			}
+			tags = append(tags, t...)
+		}
+
+		serviceTagsJSON, err := json.Marshal(nonNil(serviceTags))
+		if err != nil {
+			return nil, err
+		}
+		tagsJSON, err := json.Marshal(tags)
+		if err != nil {
+			return nil, err
+		}
+		trace.AppendRow(id, text("spanID", row), parent, text("operationName", row), text("serviceName", row),
+			json.RawMessage(serviceTagsJSON), start, duration, json.RawMessage(tagsJSON))
+	}
+
+	trace.Meta = frame.Meta
+	if trace.Meta == nil {
+		trace.Meta = &data.FrameMeta{}
+	}
+	trace.Meta.PreferredVisualization = data.VisTypeTrace
+	return trace, nil
+}
+
+func nonNil(tags []keyValue) []keyValue {
+	if tags == nil {
+		return []keyValue{}
+	}
+	return tags
+}
@@ -0,0 +1,156 @@
+package maxcompute
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/sqlds/v3"
+	"gotest.tools/assert"
+)
+
+func jsonPtr(s string) *json.RawMessage {
+	raw := json.RawMessage(s)
+	return &raw
+}
+
+func TestTraceFrame(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	root := "a1"
+	spans := func() []*data.Field {
+		return []*data.Field{
+			data.NewField("trace_id", nil, []string{"t1", "t1", "t2"}),
+			data.NewField("span_id", nil, []string{"a1", "a2", "b1"}),
+			data.NewField("parent_span_id", nil, []*string{nil, &root, nil}),
+			data.NewField("operation_name", nil, []string{"GET /", "SELECT", "POST /pay"}),
+			data.NewField("service_name", nil, []string{"web", "db", "web"}),
+			data.NewField("start_time", nil, []time.Time{start, start.Add(5 * time.Millisecond), start}),
+			data.NewField("duration", nil, []int64{20, 10, 3}),
+			data.NewField("attributes", nil, []*json.RawMessage{jsonPtr(`{"http.status":"200","b":"x"}`), jsonPtr(`{"db":"orders"}`), nil}),
+			data.NewField("resource", nil, []*json.RawMessage{jsonPtr(`{"host":"web-1"}`), jsonPtr(`{"host":"db-1"}`), nil}),
+		}
+	}
+
+	type span struct {
+		TraceID, SpanID, Parent, Operation, Service string
+		Start, Duration                             float64
+		ServiceTags, Tags                           string
+	}
+	tests := []struct {
+		description string
+		fields      []*data.Field
+		opts        TraceOptions
+		traceID     string
+		want        []span
+		wantErr     error
+	}{
+		{
+			description: "should read the spans and their MAP tags",
+			fields:      spans(),
+			traceID:     "t1",
+			want: []span{
+				{TraceID: "t1", SpanID: "a1", Operation: "GET /", Service: "web", Start: float64(start.UnixMilli()), Duration: 20,
+					ServiceTags: `[{"key":"host","value":"web-1"}]`, Tags: `[{"key":"b","value":"x"},{"key":"http.status","value":"200"}]`},
+				{TraceID: "t1", SpanID: "a2", Parent: "a1", Operation: "SELECT", Service: "db", Start: float64(start.UnixMilli() + 5), Duration: 10,
+					ServiceTags: `[{"key":"host","value":"db-1"}]`, Tags: `[{"key":"db","value":"orders"}]`},
+			},
+		},
+		{
+			description: "should map the configured columns in the time unit",
+			fields: []*data.Field{
+				data.NewField("tid", nil, []string{"t1"}),
+				data.NewField("sid", nil, []string{"s1"}),
+				data.NewField("begin_us", nil, []int64{1_709_287_200_000_000}),
+				data.NewField("elapsed_us", nil, []int64{1500}),
+				data.NewField("labels", nil, []json.RawMessage{json.RawMessage(`{"k":"v"}`)}),
+				data.NewField("extra", nil, []json.RawMessage{json.RawMessage(`{"ignored":"1"}`)}),
+			},
+			opts: TraceOptions{
+				TraceIDColumn:   "TID",
+				SpanIDColumn:    "sid",
+				StartTimeColumn: "begin_us",
+				DurationColumn:  "elapsed_us",
+				TagsColumns:     []string{"labels"},
+				TimeUnit:        "us",
+			},
+			want: []span{
+				{TraceID: "t1", SpanID: "s1", Start: 1_709_287_200_000, Duration: 1.5, ServiceTags: `[]`, Tags: `[{"key":"k","value":"v"}]`},
+			},
+		},
+		{
+			description: "should capture a result without a span id column",
+			fields: []*data.Field{
+				data.NewField("trace_id", nil, []string{"t1"}),
+				data.NewField("start_time", nil, []time.Time{start}),
+				data.NewField("duration", nil, []int64{1}),
+			},
+			wantErr: ErrorColumnRequired,
+		},
+		{
+			description: "should capture a missing tags column",
+			fields:      spans(),
+			opts:        TraceOptions{TagsColumns: []string{"tags"}},
+			wantErr:     ErrorColumnNotFound,
+		},
+		{
+			description: "should capture an invalid time unit",
+			fields:      spans(),
+			opts:        TraceOptions{TimeUnit: "min"},
+			wantErr:     ErrorInvalidTimeUnit,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			frame := data.NewFrame("A", tc.fields...)
+			frame.Meta = &data.FrameMeta{ExecutedQueryString: "SELECT * FROM spans"}
+			trace, err := traceFrame(frame, tc.opts, tc.traceID)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("%s not captured. %v", tc.wantErr, err)
+				}
+				assert.Equal(t, backend.ErrorSourceDownstream, sqlds.ErrorSource(err))
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, data.VisType(data.VisTypeTrace), trace.Meta.PreferredVisualization)
+			assert.Equal(t, "SELECT * FROM spans", trace.Meta.ExecutedQueryString)
+
+			var got []span
+			for row := 0; row < trace.Rows(); row++ {
+				s := span{
+					TraceID:     trace.Fields[0].At(row).(string),
+					SpanID:      trace.Fields[1].At(row).(string),
+					Operation:   trace.Fields[3].At(row).(string),
+					Service:     trace.Fields[4].At(row).(string),
+					ServiceTags: string(trace.Fields[5].At(row).(json.RawMessage)),
+					Start:       trace.Fields[6].At(row).(float64),
+					Duration:    trace.Fields[7].At(row).(float64),
+					Tags:        string(trace.Fields[8].At(row).(json.RawMessage)),
+				}
+				if p := trace.Fields[2].At(row).(*string); p != nil {
+					s.Parent = *p
+				}
+				got = append(got, s)
+			}
+			assert.DeepEqual(t, tc.want, got)
+		})
+	}
+}
+
+func TestFormatFramesTrace(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("trace_id", nil, []string{"t1", "t2"}),
+		data.NewField("span_id", nil, []string{"a1", "b1"}),
+		data.NewField("start_time", nil, []time.Time{time.Unix(0, 0), time.Unix(0, 0)}),
+		data.NewField("duration", nil, []int64{20, 3}),
+	)
+	q := &sqlds.Query{RefID: "A", Format: sqlds.FormatOptionTrace}
+	frames, err := formatFrames(data.Frames{frame}, q, &QueryModel{TraceID: "t2"})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(frames))
+	assert.Equal(t, 1, frames[0].Rows())
+	assert.Equal(t, "b1", frames[0].Fields[1].At(0))
+}
@@ -0,0 +1,158 @@
+// Package odpstest serves canned results through database/sql the way the
+// ODPS driver does, so conversions can be tested without a MaxCompute project.
+package odpstest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ManassehZhou/maxcompute-datasource/pkg/odpstype"
+	"github.com/aliyun/aliyun-odps-go-sdk/sqldriver"
+)
+
+// Column describes a result column the way the ODPS driver reports it.
+type Column struct {
+	Name string
+	Type string
+}
+
+// Result is served by the fake driver for a query string. Rows hold the
+// values the ODPS driver passes to database/sql for each column type.
+type Result struct {
+	Columns []Column
+	Rows    [][]driver.Value
+}
+
+var (
+	fakeOnce    sync.Once
+	fakeResults sync.Map
+)
+
+// Open opens a connection to the fake driver, closed when the test ends.
+func Open(t *testing.T) *sql.DB {
+	t.Helper()
+	fakeOnce.Do(func() { sql.Register("odps-fake", fakeDriver{}) })
+
+	db, err := sql.Open("odps-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// Query registers the result under the test name and runs it through database/sql.
+func Query(t *testing.T, result Result) *sql.Rows {
+	t.Helper()
+	db := Open(t)
+
+	query := t.Name()
+	fakeResults.Store(query, result)
+	t.Cleanup(func() { fakeResults.Delete(query) })
+
+	rows, err := db.QueryContext(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rows.Close() })
+	return rows
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, fmt.Errorf("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, fmt.Errorf("not supported") }
+
+func (fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	result, ok := fakeResults.Load(query)
+	if !ok {
+		return nil, fmt.Errorf("unexpected query %s", query)
+	}
+	return &fakeRows{result: result.(Result)}, nil
+}
+
+type fakeRows struct {
+	result Result
+	next   int
+}
+
+func (r *fakeRows) Columns() []string {
+	names := make([]string, len(r.result.Columns))
+	for i, c := range r.result.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dst []driver.Value) error {
+	if r.next == len(r.result.Rows) {
+		return io.EOF
+	}
+	copy(dst, r.result.Rows[r.next])
+	r.next++
+	return nil
+}
+
+func (r *fakeRows) ColumnTypeDatabaseTypeName(i int) string {
+	return r.result.Columns[i].Type
+}
+
+// ColumnTypeScanType mirrors the scan types of the ODPS driver for nullable columns.
+func (r *fakeRows) ColumnTypeScanType(i int) reflect.Type {
+	typ, err := odpstype.Parse(r.result.Columns[i].Type)
+	if err != nil {
+		return reflect.TypeOf("")
+	}
+	switch typ.Name {
+	case "BIGINT":
+		return reflect.TypeOf(sqldriver.NullInt64{})
+	case "INT":
+		return reflect.TypeOf(sqldriver.NullInt32{})
+	case "SMALLINT":
+		return reflect.TypeOf(sqldriver.NullInt16{})
+	case "TINYINT":
+		return reflect.TypeOf(sqldriver.NullInt8{})
+	case "DOUBLE":
+		return reflect.TypeOf(sqldriver.NullFloat64{})
+	case "FLOAT":
+		return reflect.TypeOf(sqldriver.NullFloat32{})
+	case "STRING", odpstype.Char, odpstype.Varchar:
+		return reflect.TypeOf(sqldriver.NullString{})
+	case "BOOLEAN":
+		return reflect.TypeOf(sqldriver.NullBool{})
+	case "BINARY":
+		return reflect.TypeOf(sqldriver.Binary{})
+	case "DATE":
+		return reflect.TypeOf(sqldriver.NullDate{})
+	case "DATETIME":
+		return reflect.TypeOf(sqldriver.NullDateTime{})
+	case "TIMESTAMP":
+		return reflect.TypeOf(sqldriver.NullTimeStamp{})
+	case "TIMESTAMP_NTZ":
+		return reflect.TypeOf(sqldriver.NullTimeStampNtz{})
+	case "JSON":
+		return reflect.TypeOf(sqldriver.Json{})
+	case odpstype.Decimal:
+		return reflect.TypeOf(sqldriver.Decimal{})
+	case odpstype.Map:
+		return reflect.TypeOf(sqldriver.Map{})
+	case odpstype.Array:
+		return reflect.TypeOf(sqldriver.Array{})
+	case odpstype.Struct:
+		return reflect.TypeOf(sqldriver.Struct{})
+	}
+	return reflect.TypeOf("")
+}
@@ -0,0 +1,363 @@
+// Package odpstype parses MaxCompute type strings, such as the database type
+// names of result columns, into a typed tree.
+package odpstype
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Names of the types with parameters or nested types.
+const (
+	Array   = "ARRAY"
+	Map     = "MAP"
+	Struct  = "STRUCT"
+	Decimal = "DECIMAL"
+	Char    = "CHAR"
+	Varchar = "VARCHAR"
+)
+
+// Default precision and scale of a DECIMAL declared without them.
+const (
+	DefaultDecimalPrecision = 38
+	DefaultDecimalScale     = 18
+)
+
+var ErrorInvalidType = errors.New("invalid MaxCompute type")
+
+// Type is a parsed MaxCompute type.
+type Type struct {
+	// Name is the upper case name of the type, such as BIGINT or ARRAY.
+	Name string
+	// Params are the parenthesised parameters, such as the length of
+	// VARCHAR(4) or the precision and scale of DECIMAL(38,18).
+	Params []int
+	// Elem is the element type of an ARRAY.
+	Elem *Type
+	// Key and Value are the key and value types of a MAP.
+	Key, Value *Type
+	// Fields are the fields of a STRUCT, in declaration order.
+	Fields []Field
+}
+
+// Field is a field of a STRUCT type.
+type Field struct {
+	Name string
+	Type *Type
+}
+
+// Parse parses a type string such as DECIMAL(10,2) or ARRAY<STRUCT<a:INT>>.
+// Names are case insensitive. Unknown names are accepted, so newer types are
+// parsed as long as they take no nested types.
+func Parse(s string) (*Type, error) {
+	p := &parser{s: s}
+	t, err := p.parseType()
+	if err == nil {
+		p.skipSpace()
+		if p.pos < len(p.s) {
+			err = p.errorf("unexpected %q", p.s[p.pos:])
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// String returns the canonical form of the type.
+func (t *Type) String() string {
+	var b strings.Builder
+	t.write(&b)
+	return b.String()
+}
+
+func (t *Type) write(b *strings.Builder) {
+	b.WriteString(t.Name)
+	if len(t.Params) > 0 {
+		b.WriteByte('(')
+		for i, p := range t.Params {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Itoa(p))
+		}
+		b.WriteByte(')')
+	}
+	switch t.Name {
+	case Array:
+		b.WriteByte('<')
+		t.Elem.write(b)
+		b.WriteByte('>')
+	case Map:
+		b.WriteByte('<')
+		t.Key.write(b)
+		b.WriteByte(',')
+		t.Value.write(b)
+		b.WriteByte('>')
+	case Struct:
+		b.WriteByte('<')
+		for i, f := range t.Fields {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(quoteName(f.Name))
+			b.WriteByte(':')
+			f.Type.write(b)
+		}
+		b.WriteByte('>')
+	}
+}
+
+// DecimalParams returns the precision and scale of a DECIMAL, with the
+// defaults when it is declared without them.
+func (t *Type) DecimalParams() (precision, scale int, ok bool) {
+	if t.Name != Decimal {
+		return 0, 0, false
+	}
+	switch len(t.Params) {
+	case 0:
+		return DefaultDecimalPrecision, DefaultDecimalScale, true
+	case 1:
+		return t.Params[0], 0, true
+	}
+	return t.Params[0], t.Params[1], true
+}
+
+// Length returns the declared length of a CHAR or VARCHAR.
+func (t *Type) Length() (int, bool) {
+	if (t.Name != Char && t.Name != Varchar) || len(t.Params) != 1 {
+		return 0, false
+	}
+	return t.Params[0], true
+}
+
+// quoteName quotes a STRUCT field name that is not a plain identifier.
+func quoteName(name string) string {
+	for i, r := range name {
+		if !isIdentRune(r) || (i == 0 && r >= '0' && r <= '9') {
+			return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+		}
+	}
+	if name == "" {
+		return "``"
+	}
+	return name
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w %q at %d: %s", ErrorInvalidType, p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// peek returns the next non space byte, 0 at the end of the string.
+func (p *parser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *parser) expect(c byte) error {
+	if p.peek() != c {
+		if p.pos >= len(p.s) {
+			return p.errorf("expected %q, got the end", c)
+		}
+		return p.errorf("expected %q, got %q", c, p.s[p.pos])
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) ident() (string, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && isIdentRune(rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf("expected a name")
+	}
+	return p.s[start:p.pos], nil
+}
+
+// fieldName reads a STRUCT field name, plain or quoted with backquotes.
+func (p *parser) fieldName() (string, error) {
+	if p.peek() != '`' {
+		return p.ident()
+	}
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		if p.s[p.pos] != '`' {
+			b.WriteByte(p.s[p.pos])
+			continue
+		}
+		if p.pos+1 < len(p.s) && p.s[p.pos+1] == '`' {
+			b.WriteByte('`')
+			p.pos++
+			continue
+		}
+		p.pos++
+		return b.String(), nil
+	}
+	return "", p.errorf("missing closing backquote")
+}
+
+func (p *parser) parseType() (*Type, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	t := &Type{Name: strings.ToUpper(name)}
+
+	if p.peek() == '(' {
+		if t.Params, err = p.params(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch t.Name {
+	case Array:
+		if err := p.expect('<'); err != nil {
+			return nil, err
+		}
+		if t.Elem, err = p.parseType(); err != nil {
+			return nil, err
+		}
+	case Map:
+		if err := p.expect('<'); err != nil {
+			return nil, err
+		}
+		if t.Key, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(','); err != nil {
+			return nil, err
+		}
+		if t.Value, err = p.parseType(); err != nil {
+			return nil, err
+		}
+	case Struct:
+		if err := p.expect('<'); err != nil {
+			return nil, err
+		}
+		if t.Fields, err = p.fields(); err != nil {
+			return nil, err
+		}
+	default:
+		return t, p.checkParams(t)
+	}
+	if err := p.expect('>'); err != nil {
+		return nil, err
+	}
+	if len(t.Params) > 0 {
+		return nil, p.errorf("%s takes no parameters", t.Name)
+	}
+	return t, nil
+}
+
+func (p *parser) params() ([]int, error) {
+	p.pos++
+	var params []int
+	for {
+		p.skipSpace()
+		start := p.pos
+		for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+			p.pos++
+		}
+		n, err := strconv.Atoi(p.s[start:p.pos])
+		if err != nil {
+			return nil, p.errorf("expected a number")
+		}
+		params = append(params, n)
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	return params, p.expect(')')
+}
+
+// checkParams validates the parameters of the types that take some.
+func (p *parser) checkParams(t *Type) error {
+	switch t.Name {
+	case Decimal:
+		if len(t.Params) > 2 {
+			return p.errorf("DECIMAL takes a precision and a scale")
+		}
+		if precision, scale, _ := t.DecimalParams(); scale > precision {
+			return p.errorf("DECIMAL scale %d exceeds its precision %d", scale, precision)
+		}
+	case Char, Varchar:
+		if len(t.Params) != 1 {
+			return p.errorf("%s takes a length", t.Name)
+		}
+	}
+	return nil
+}
+
+func (p *parser) fields() ([]Field, error) {
+	var fields []Field
+	for {
+		name, err := p.fieldName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+		typ, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.skipComment(); err != nil {
+			return nil, err
+		}
+		fields = append(fields, Field{Name: name, Type: typ})
+		if p.peek() != ',' {
+			return fields, nil
+		}
+		p.pos++
+	}
+}
+
+// skipComment skips the COMMENT 'text' a STRUCT field may be declared with.
+func (p *parser) skipComment() error {
+	p.skipSpace()
+	const keyword = "COMMENT"
+	if len(p.s)-p.pos <= len(keyword) || !strings.EqualFold(p.s[p.pos:p.pos+len(keyword)], keyword) ||
+		isIdentRune(rune(p.s[p.pos+len(keyword)])) {
+		return nil
+	}
+	p.pos += len(keyword)
+	quote := p.peek()
+	if quote != '\'' && quote != '"' {
+		return p.errorf("expected a quoted comment")
+	}
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		switch p.s[p.pos] {
+		case '\\':
+			p.pos++
+		case quote:
+			p.pos++
+			return nil
+		}
+	}
+	return p.errorf("missing closing quote")
+}
@@ -0,0 +1,106 @@
+package odpstype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  *Type
+		str   string
+	}{
+		{input: "BIGINT", want: &Type{Name: "BIGINT"}, str: "BIGINT"},
+		{input: "bigint", want: &Type{Name: "BIGINT"}, str: "BIGINT"},
+		{input: "VARCHAR(4)", want: &Type{Name: Varchar, Params: []int{4}}, str: "VARCHAR(4)"},
+		{input: "decimal( 38 , 18 )", want: &Type{Name: Decimal, Params: []int{38, 18}}, str: "DECIMAL(38,18)"},
+		{input: "TIMESTAMP_NTZ", want: &Type{Name: "TIMESTAMP_NTZ"}, str: "TIMESTAMP_NTZ"},
+		{
+			input: "ARRAY<STRUCT<a:INT>>",
+			want:  &Type{Name: Array, Elem: &Type{Name: Struct, Fields: []Field{{Name: "a", Type: &Type{Name: "INT"}}}}},
+			str:   "ARRAY<STRUCT<a:INT>>",
+		},
+		{
+			input: "map<string, decimal(10,2)>",
+			want:  &Type{Name: Map, Key: &Type{Name: "STRING"}, Value: &Type{Name: Decimal, Params: []int{10, 2}}},
+			str:   "MAP<STRING,DECIMAL(10,2)>",
+		},
+		{
+			input: "STRUCT<`first name`:STRING COMMENT 'given, name', Nested:STRUCT<x:DOUBLE>>",
+			want: &Type{Name: Struct, Fields: []Field{
+				{Name: "first name", Type: &Type{Name: "STRING"}},
+				{Name: "Nested", Type: &Type{Name: Struct, Fields: []Field{{Name: "x", Type: &Type{Name: "DOUBLE"}}}}},
+			}},
+			str: "STRUCT<`first name`:STRING,Nested:STRUCT<x:DOUBLE>>",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := Parse(tc.input)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+			require.Equal(t, tc.str, got.String())
+
+			again, err := Parse(got.String())
+			require.NoError(t, err)
+			require.Equal(t, got, again)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"ARRAY",
+		"ARRAY<INT",
+		"ARRAY<INT>>",
+		"MAP<STRING>",
+		"STRUCT<>",
+		"STRUCT<a INT>",
+		"STRUCT<`a:INT>",
+		"VARCHAR",
+		"DECIMAL(10,2,1)",
+		"DECIMAL(2,10)",
+		"DECIMAL(a)",
+		"ARRAY(1)<INT>",
+		"BIGINT BIGINT",
+	} {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(input)
+			require.ErrorIs(t, err, ErrorInvalidType)
+		})
+	}
+}
+
+func TestParams(t *testing.T) {
+	for _, tc := range []struct {
+		input            string
+		precision, scale int
+		ok               bool
+	}{
+		{input: "DECIMAL", precision: 38, scale: 18, ok: true},
+		{input: "DECIMAL(10)", precision: 10, ok: true},
+		{input: "DECIMAL(10,2)", precision: 10, scale: 2, ok: true},
+		{input: "DOUBLE"},
+	} {
+		typ, err := Parse(tc.input)
+		require.NoError(t, err)
+		precision, scale, ok := typ.DecimalParams()
+		require.Equal(t, tc.ok, ok, tc.input)
+		require.Equal(t, tc.precision, precision, tc.input)
+		require.Equal(t, tc.scale, scale, tc.input)
+	}
+
+	typ, err := Parse("CHAR(8)")
+	require.NoError(t, err)
+	length, ok := typ.Length()
+	require.True(t, ok)
+	require.Equal(t, 8, length)
+
+	typ, err = Parse("STRING")
+	require.NoError(t, err)
+	_, ok = typ.Length()
+	require.False(t, ok)
+}
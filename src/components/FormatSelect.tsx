@@ -15,7 +15,6 @@ export const FormatSelect = (props: Props) => {
         {
           label: formatLabels.AUTO,
           value: Format.AUTO,
-          isDisabled: true
         },
         {
           label: formatLabels.TABLE,
@@ -1,9 +1,20 @@
 import React from 'react';
 // import { QueryTypeSwitcher } from 'components/QueryTypeSwitcher';
-import { Button } from '@grafana/ui';
-import { EditorHeader, FlexItem } from '@grafana/experimental';
-import { Format, MCQuery, QueryType } from 'types';
+import { Button, InlineField, InlineSwitch, Input } from '@grafana/ui';
+import { EditorHeader, FlexItem, InlineSelect } from '@grafana/experimental';
+import {
+  BinaryMode,
+  DecimalMode,
+  Format,
+  IntervalMode,
+  LogsColumns,
+  MCQuery,
+  QueryType,
+  TimeUnit,
+  TraceColumns,
+} from 'types';
 import { FormatSelect } from './FormatSelect';
+import { selectors } from 'selectors';
 
 interface QueryHeaderProps {
   query: MCQuery;
@@ -28,8 +39,212 @@ export const QueryHeader = ({ query, onChange, onRunQuery }: QueryHeaderProps) =
     }
   };
 
+  const onTimeShiftChange = (e: React.FormEvent<HTMLInputElement>) => {
+    const timeShift = e.currentTarget.value.trim() || undefined;
+    if (timeShift !== query.timeShift) {
+      onChange({ ...query, timeShift });
+    }
+  };
+
+  const onFlattenStructsChange = (e: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...query, flattenStructs: e.currentTarget.checked || undefined });
+  };
+
+  const onColumnCommentsChange = (e: React.FormEvent<HTMLInputElement>) => {
+    onChange({ ...query, columnComments: e.currentTarget.checked || undefined });
+  };
+
+  const onLogsColumnChange = (key: keyof LogsColumns) => (e: React.FormEvent<HTMLInputElement>) => {
+    const column = e.currentTarget.value.trim() || undefined;
+    if (column !== query.logs?.[key]) {
+      onChange({ ...query, logs: { ...query.logs, [key]: column } });
+    }
+  };
+
+  const onTraceColumnChange =
+    (key: Exclude<keyof TraceColumns, 'tagsColumns' | 'timeUnit'>) => (e: React.FormEvent<HTMLInputElement>) => {
+      const column = e.currentTarget.value.trim() || undefined;
+      if (column !== query.trace?.[key]) {
+        onChange({ ...query, trace: { ...query.trace, [key]: column } });
+      }
+    };
+
+  const onTraceIdChange = (e: React.FormEvent<HTMLInputElement>) => {
+    const traceId = e.currentTarget.value.trim() || undefined;
+    if (traceId !== query.traceId) {
+      onChange({ ...query, traceId });
+    }
+  };
+
+  const onSplitByChange = (e: React.FormEvent<HTMLInputElement>) => {
+    const columns = e.currentTarget.value
+      .split(',')
+      .map((column) => column.trim())
+      .filter((column) => column !== '');
+    const splitBy = columns.length ? columns : undefined;
+    if (splitBy?.join(',') !== query.splitBy?.join(',')) {
+      onChange({ ...query, splitBy });
+    }
+  };
+
+  const onMaxSeriesChange = (e: React.FormEvent<HTMLInputElement>) => {
+    const value = parseInt(e.currentTarget.value, 10);
+    const maxSeries = value > 0 ? value : undefined;
+    if (maxSeries !== query.maxSeries) {
+      onChange({ ...query, maxSeries });
+    }
+  };
+
+  const onExplodeChange = (e: React.FormEvent<HTMLInputElement>) => {
+    const explode = e.currentTarget.value.trim() || undefined;
+    if (explode !== query.explode) {
+      onChange({ ...query, explode });
+    }
+  };
+
+  const decimalModeLabels = selectors.components.QueryEditor.DecimalMode;
+  const decimalModeOptions = selectors.components.ConfigEditor.DecimalMode.options;
+  const intervalModeLabels = selectors.components.QueryEditor.IntervalMode;
+  const intervalModeOptions = selectors.components.ConfigEditor.IntervalMode.options;
+  const binaryModeLabels = selectors.components.QueryEditor.BinaryMode;
+
+  const timeShiftLabels = selectors.components.QueryEditor.TimeShift;
+  const flattenStructsLabels = selectors.components.QueryEditor.FlattenStructs;
+  const explodeLabels = selectors.components.QueryEditor.Explode;
+  const splitByLabels = selectors.components.QueryEditor.SplitBy;
+  const maxSeriesLabels = selectors.components.QueryEditor.MaxSeries;
+  const columnCommentsLabels = selectors.components.QueryEditor.ColumnComments;
+  const logsColumnsLabels = selectors.components.QueryEditor.LogsColumns;
+  const logsColumns: Array<keyof LogsColumns> = ['timeColumn', 'bodyColumn', 'levelColumn'];
+  const traceIdLabels = selectors.components.QueryEditor.TraceID;
+  const traceColumnsLabels = selectors.components.QueryEditor.TraceColumns;
+  const traceColumns = [
+    'traceIdColumn',
+    'spanIdColumn',
+    'parentSpanIdColumn',
+    'operationNameColumn',
+    'serviceNameColumn',
+    'startTimeColumn',
+    'durationColumn',
+  ] as const;
+  const timeUnitLabels = selectors.components.QueryEditor.TimeUnit;
+
   return (
     <EditorHeader>
+      <InlineField label={timeShiftLabels.label} tooltip={timeShiftLabels.tooltip}>
+        <Input
+          width={10}
+          placeholder={timeShiftLabels.placeholder}
+          defaultValue={query.timeShift}
+          onBlur={onTimeShiftChange}
+        />
+      </InlineField>
+      <InlineField label={flattenStructsLabels.label} tooltip={flattenStructsLabels.tooltip}>
+        <InlineSwitch value={query.flattenStructs ?? false} onChange={onFlattenStructsChange} />
+      </InlineField>
+      <InlineField label={columnCommentsLabels.label} tooltip={columnCommentsLabels.tooltip}>
+        <InlineSwitch value={query.columnComments ?? false} onChange={onColumnCommentsChange} />
+      </InlineField>
+      <InlineField label={explodeLabels.label} tooltip={explodeLabels.tooltip}>
+        <Input
+          width={16}
+          placeholder={explodeLabels.placeholder}
+          defaultValue={query.explode}
+          onBlur={onExplodeChange}
+        />
+      </InlineField>
+      <InlineField label={splitByLabels.label} tooltip={splitByLabels.tooltip}>
+        <Input
+          width={20}
+          placeholder={splitByLabels.placeholder}
+          defaultValue={query.splitBy?.join(', ')}
+          onBlur={onSplitByChange}
+        />
+      </InlineField>
+      {query.splitBy && (
+        <InlineField label={maxSeriesLabels.label} tooltip={maxSeriesLabels.tooltip}>
+          <Input
+            width={10}
+            type="number"
+            placeholder={maxSeriesLabels.placeholder}
+            defaultValue={query.maxSeries}
+            onBlur={onMaxSeriesChange}
+          />
+        </InlineField>
+      )}
+      <InlineSelect
+        label={decimalModeLabels.label}
+        placeholder={decimalModeLabels.placeholder}
+        isClearable
+        value={query.decimalMode}
+        options={Object.values(DecimalMode).map((mode) => ({ label: decimalModeOptions[mode], value: mode }))}
+        onChange={(e) => onChange({ ...query, decimalMode: e?.value })}
+      />
+      <InlineSelect
+        label={intervalModeLabels.label}
+        placeholder={intervalModeLabels.placeholder}
+        isClearable
+        value={query.intervalMode}
+        options={Object.values(IntervalMode).map((mode) => ({ label: intervalModeOptions[mode], value: mode }))}
+        onChange={(e) => onChange({ ...query, intervalMode: e?.value })}
+      />
+      <InlineSelect
+        label={binaryModeLabels.label}
+        placeholder={binaryModeLabels.placeholder}
+        isClearable
+        value={query.binaryMode}
+        options={Object.values(BinaryMode).map((mode) => ({ label: binaryModeLabels.options[mode], value: mode }))}
+        onChange={(e) => onChange({ ...query, binaryMode: e?.value })}
+      />
+      {query.format === Format.LOGS &&
+        logsColumns.map((key) => (
+          <InlineField
+            key={key}
+            label={logsColumnsLabels[key].label}
+            tooltip={logsColumnsLabels.tooltip.replace('%s', logsColumnsLabels[key].label.toLowerCase())}
+          >
+            <Input
+              width={12}
+              placeholder={logsColumnsLabels[key].placeholder}
+              defaultValue={query.logs?.[key]}
+              onBlur={onLogsColumnChange(key)}
+            />
+          </InlineField>
+        ))}
+      {query.format === Format.TRACE && (
+        <>
+          <InlineField label={traceIdLabels.label} tooltip={traceIdLabels.tooltip}>
+            <Input
+              width={20}
+              placeholder={traceIdLabels.placeholder}
+              defaultValue={query.traceId}
+              onBlur={onTraceIdChange}
+            />
+          </InlineField>
+          {traceColumns.map((key) => (
+            <InlineField
+              key={key}
+              label={traceColumnsLabels[key].label}
+              tooltip={traceColumnsLabels.tooltip.replace('%s', traceColumnsLabels[key].label.toLowerCase())}
+            >
+              <Input
+                width={12}
+                placeholder={traceColumnsLabels[key].placeholder}
+                defaultValue={query.trace?.[key]}
+                onBlur={onTraceColumnChange(key)}
+              />
+            </InlineField>
+          ))}
+          <InlineSelect
+            label={timeUnitLabels.label}
+            placeholder={timeUnitLabels.placeholder}
+            isClearable
+            value={query.trace?.timeUnit}
+            options={Object.values(TimeUnit).map((unit) => ({ label: timeUnitLabels.options[unit], value: unit }))}
+            onChange={(e) => onChange({ ...query, trace: { ...query.trace, timeUnit: e?.value } })}
+          />
+        </>
+      )}
       <FlexItem grow={1} />
       <Button variant="primary" icon="play" size="sm" onClick={onRunQuery}>
         Run query
@@ -1,8 +1,8 @@
-import React, { useState } from 'react';
+import React, { useEffect, useState } from 'react';
 import { QueryEditorProps } from '@grafana/data';
-import { CodeEditor } from '@grafana/ui';
+import { CodeEditor, CodeEditorSuggestionItem, CodeEditorSuggestionItemKind } from '@grafana/ui';
 import { selectors } from 'selectors';
-import { MCConfig, MCQuery, MCSQLQuery, QueryType } from 'types';
+import { MCConfig, MCQuery, MCSQLQuery, MacroInfo, QueryType } from 'types';
 import { DataSource } from 'datasource';
 import { styles } from 'styles';
 
@@ -16,8 +16,13 @@ interface Expand {
 
 export const SQLEditor = (props: SQLEditorProps) => {
   const defaultHeight = '150px';
-  const { query, onRunQuery, onChange } = props;
+  const { query, onRunQuery, onChange, datasource } = props;
   const [codeEditor, setCodeEditor] = useState<any>();
+  const [macros, setMacros] = useState<MacroInfo[]>([]);
+
+  useEffect(() => {
+    datasource?.getMacros?.().then(setMacros);
+  }, [datasource]);
   const [expand, setExpand] = useState<Expand>({
     height: defaultHeight,
     icon: 'plus',
@@ -80,11 +85,20 @@ export const SQLEditor = (props: SQLEditorProps) => {
         showLineNumbers={true}
         onBlur={(text) => onChange({ ...query, rawSql: text })}
         onEditorDidMount={(editor: any) => handleMount(editor)}
+        getSuggestions={() => macroSuggestions(macros)}
       />
     </div>
   );
 };
 
+const macroSuggestions = (macros: MacroInfo[]): CodeEditorSuggestionItem[] =>
+  macros.map((m) => ({
+    label: `$__${m.name}`,
+    kind: CodeEditorSuggestionItemKind.Method,
+    detail: m.args.length ? `$__${m.name}(${m.args.map((a) => (a.optional ? `[${a.name}]` : a.name)).join(', ')})` : `$__${m.name}`,
+    documentation: [m.description, ...m.args.map((a) => `${a.name}: ${a.description}`), `Example: ${m.example}`].join('\n'),
+  }));
+
 const getEditorHeight = (editor: any): number | undefined => {
   const editorElement = editor.getDomNode();
   if (!editorElement) {
@@ -1,11 +1,38 @@
-import { DataSourceInstanceSettings, CoreApp, ScopedVars, VariableSupportType, DataQueryRequest } from '@grafana/data';
+import {
+  DataSourceInstanceSettings,
+  CoreApp,
+  ScopedVars,
+  VariableSupportType,
+  DataQueryRequest,
+  DataSourceWithSupplementaryQueriesSupport,
+  SupplementaryQueryType,
+} from '@grafana/data';
 import { DataSourceWithBackend, getTemplateSrv } from '@grafana/runtime';
 
-import { MCQuery, MCConfig, defaultMCSQLQuery } from './types';
+import { Format, MCQuery, MCConfig, MacroInfo, MacroVariable, QueryType, defaultMCSQLQuery } from './types';
 import { SQLEditor } from './components/SQLEditor'
 import { uniqueId } from 'lodash';
 
-export class DataSource extends DataSourceWithBackend<MCQuery, MCConfig> {
+const variableMacroRegex = /\$__(in|values)\(/g;
+const variableRefRegex = /\$(\w+)|\$\{(\w+)(?::\w+)?\}|\[\[(\w+)(?::\w+)?\]\]/g;
+
+// closingParen returns the index of the parenthesis closing the one opened before start.
+function closingParen(sql: string, start: number): number {
+  let depth = 1;
+  for (let i = start; i < sql.length; i++) {
+    if (sql[i] === '(') {
+      depth++;
+    } else if (sql[i] === ')' && --depth === 0) {
+      return i;
+    }
+  }
+  return sql.length - 1;
+}
+
+export class DataSource
+  extends DataSourceWithBackend<MCQuery, MCConfig>
+  implements DataSourceWithSupplementaryQueriesSupport<MCQuery>
+{
   constructor(instanceSettings: DataSourceInstanceSettings<MCConfig>) {
     super(instanceSettings);
     this.variables = {
@@ -18,12 +45,55 @@ export class DataSource extends DataSourceWithBackend<MCQuery, MCConfig> {
         return this.query({ ...request, targets: queries });
       }
     };
+    // Annotation queries are regular SQL queries whose time, timeEnd, title,
+    // text and tags columns the backend turns into annotations.
+    this.annotations = {
+      prepareQuery: (anno) =>
+        anno.target && { ...anno.target, refId: anno.target.refId || 'Anno', queryType: QueryType.ANNOTATION },
+    };
+  }
+
+  private macros?: Promise<MacroInfo[]>;
+
+  getMacros(): Promise<MacroInfo[]> {
+    if (!this.macros) {
+      this.macros = this.getResource<MacroInfo[]>('macros').catch(() => {
+        this.macros = undefined;
+        return [];
+      });
+    }
+    return this.macros;
   }
 
   getDefaultQuery(_: CoreApp): Partial<MCQuery> {
     return defaultMCSQLQuery; 
   }
 
+  getSupportedSupplementaryQueryTypes(): SupplementaryQueryType[] {
+    return [SupplementaryQueryType.LogsVolume];
+  }
+
+  // The logs volume re-runs a logs query, with the backend counting its lines per level over time.
+  getSupplementaryQuery(type: SupplementaryQueryType, query: MCQuery): MCQuery | undefined {
+    if (type !== SupplementaryQueryType.LogsVolume || query.format !== Format.LOGS) {
+      return undefined;
+    }
+    return { ...query, refId: `logs-volume-${query.refId}`, logsVolume: true };
+  }
+
+  getDataProvider(
+    type: SupplementaryQueryType,
+    request: DataQueryRequest<MCQuery>
+  ): ReturnType<DataSource['query']> | undefined {
+    const targets = request.targets
+      .map((query) => this.getSupplementaryQuery(type, query))
+      .filter((query): query is MCQuery => query !== undefined);
+    if (targets.length === 0) {
+      return undefined;
+    }
+    return this.query({ ...request, targets });
+  }
+
   filterQuery(query: MCQuery): boolean {
     return query.hide !== true && query.rawSql !== '';
   }
@@ -32,11 +102,48 @@ export class DataSource extends DataSourceWithBackend<MCQuery, MCConfig> {
     let rawQuery = query.rawSql || '';
     let templateSrv = getTemplateSrv();
 
-    rawQuery = templateSrv.replace(rawQuery, scopedVars)
+    // Variables passed to $__in and $__values are left in place and sent as
+    // raw values, so the backend quotes them instead of splicing text into the SQL.
+    const variables: Record<string, MacroVariable> = {};
+    let interpolated = '';
+    let last = 0;
+    for (const match of rawQuery.matchAll(variableMacroRegex)) {
+      if (match.index! < last) {
+        continue;
+      }
+      const end = closingParen(rawQuery, match.index! + match[0].length);
+      const call = rawQuery.slice(match.index, end + 1);
+      interpolated += templateSrv.replace(rawQuery.slice(last, match.index), scopedVars);
+      interpolated += call;
+      last = end + 1;
+
+      const args = rawQuery.slice(match.index! + match[0].length, end);
+      for (const ref of args.matchAll(variableRefRegex)) {
+        const name = ref[1] || ref[2] || ref[3];
+        variables[name] = this.variableValues(name, scopedVars);
+      }
+    }
+    rawQuery = interpolated + templateSrv.replace(rawQuery.slice(last), scopedVars);
 
     return {
       ...query,
-      rawSql: rawQuery
+      rawSql: rawQuery,
+      variables,
     }
   }
+
+  private variableValues(name: string, scopedVars: ScopedVars): MacroVariable {
+    const templateSrv = getTemplateSrv();
+    const variable: any = templateSrv.getVariables().find((v) => v.name === name);
+    const current = scopedVars[name]?.value ?? variable?.current?.value;
+    const all = current === '$__all' || (Array.isArray(current) && current.includes('$__all'));
+
+    let values: string[] = [];
+    templateSrv.replace(`\${${name}}`, scopedVars, (value: string | string[]) => {
+      values = (Array.isArray(value) ? value : [value]).map(String);
+      return '';
+    });
+
+    return { values, all };
+  }
 }
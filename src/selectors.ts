@@ -47,6 +47,50 @@ export const Components = {
             placeholder: '',
             tooltip: 'MaxCompute Tunnel Quota Name',
         },
+        Timezone: {
+            label: 'Timezone',
+            placeholder: 'UTC',
+            tooltip: 'Project timezone used to render time macro literals, e.g. Asia/Shanghai. UTC when empty',
+        },
+        TimestampNtzTimezone: {
+            label: 'TIMESTAMP_NTZ timezone',
+            placeholder: 'UTC',
+            tooltip: 'Timezone the wall clocks of TIMESTAMP_NTZ values are read in, e.g. Asia/Shanghai',
+        },
+        MaxSeries: {
+            label: 'Max series',
+            placeholder: '100',
+            tooltip: 'Number of frames a query split by columns returns at most. Further series are dropped with a warning',
+        },
+        NestedTypesAsString: {
+            label: 'Nested types as strings',
+            tooltip: 'Render ARRAY, MAP and STRUCT values as ODPS literal strings instead of JSON',
+        },
+        DecimalMode: {
+            label: 'Decimal mode',
+            tooltip: 'How DECIMAL values are returned. Floats can be graphed and alerted on but round values beyond 15 digits',
+            options: {
+                string: 'String',
+                float: 'Float',
+                scaled: 'Scaled integer',
+            },
+        },
+        IntervalMode: {
+            label: 'Interval mode',
+            tooltip: 'How INTERVAL values are returned. Numeric returns day-time intervals in milliseconds and year-month intervals in months, rendered as durations',
+            options: {
+                string: 'String',
+                numeric: 'Numeric',
+            },
+        },
+        CustomMacros: {
+            title: 'Custom Macros',
+            name: 'Name',
+            params: 'Parameters',
+            paramsPlaceholder: 'column, region',
+            body: 'Body',
+            bodyPlaceholder: "${column} IN ('id', 'sg')",
+        },
         Others: {},
     },
     QueryEditor: {
@@ -66,6 +110,86 @@ export const Components = {
                 TRACE: 'Trace',
             },
         },
+        TimeShift: {
+            label: 'Time shift',
+            placeholder: '7d',
+            tooltip: 'Moves the result time values forward, to overlay a series queried with $__timeFilterShift',
+        },
+        FlattenStructs: {
+            label: 'Flatten structs',
+            tooltip: 'Returns one typed field per STRUCT field, named like col.field, instead of a JSON field',
+        },
+        ColumnComments: {
+            label: 'Column comments',
+            tooltip: 'Names and describes the fields of a single table select from the table column comments. A [unit:percent] tag in a comment sets the unit',
+        },
+        DecimalMode: {
+            label: 'Decimals',
+            tooltip: 'Overrides the datasource decimal mode for this query',
+            placeholder: 'Default',
+        },
+        IntervalMode: {
+            label: 'Intervals',
+            tooltip: 'Overrides the datasource interval mode for this query',
+            placeholder: 'Default',
+        },
+        BinaryMode: {
+            label: 'Binaries',
+            tooltip: 'How BINARY values are returned. UTF-8 replaces invalid bytes, length returns the size in bytes',
+            placeholder: 'Base64',
+            options: {
+                base64: 'Base64',
+                hex: 'Hex',
+                utf8: 'UTF-8',
+                length: 'Length',
+            },
+        },
+        LogsColumns: {
+            tooltip: 'Column read as the %s of log lines. Detected by name when empty, the other columns become labels',
+            timeColumn: { label: 'Time', placeholder: 'time' },
+            bodyColumn: { label: 'Body', placeholder: 'message' },
+            levelColumn: { label: 'Level', placeholder: 'level' },
+        },
+        SplitBy: {
+            label: 'Split by',
+            placeholder: 'region, channel',
+            tooltip: 'Comma separated columns. One frame is returned per distinct combination of their values, with the values as labels',
+        },
+        MaxSeries: {
+            label: 'Max series',
+            placeholder: 'default',
+            tooltip: 'Overrides the data source maximum number of series of a split query',
+        },
+        TraceID: {
+            label: 'Trace ID',
+            placeholder: 'all traces',
+            tooltip: 'Keep only the spans of this trace. Also the value of the $__traceId macro',
+        },
+        TraceColumns: {
+            tooltip: 'Column read as the %s of spans. Detected by name when empty, MAP columns become span tags',
+            traceIdColumn: { label: 'Trace', placeholder: 'trace_id' },
+            spanIdColumn: { label: 'Span', placeholder: 'span_id' },
+            parentSpanIdColumn: { label: 'Parent', placeholder: 'parent_span_id' },
+            operationNameColumn: { label: 'Operation', placeholder: 'operation_name' },
+            serviceNameColumn: { label: 'Service', placeholder: 'service_name' },
+            startTimeColumn: { label: 'Start', placeholder: 'start_time' },
+            durationColumn: { label: 'Duration', placeholder: 'duration' },
+        },
+        TimeUnit: {
+            label: 'Time unit',
+            placeholder: 'ms',
+            options: {
+                s: 'Seconds',
+                ms: 'Milliseconds',
+                us: 'Microseconds',
+                ns: 'Nanoseconds',
+            },
+        },
+        Explode: {
+            label: 'Explode',
+            placeholder: 'column',
+            tooltip: 'ARRAY or MAP column to expand into one row per element, as col.index or col.key and col.value fields',
+        },
         Types: {
             label: 'Query Type',
             tooltip: 'Query Type',
@@ -5,9 +5,108 @@ export const defaultQuery: Partial<MCQuery> = {};
 export enum QueryType {
   SQL = 'sql',
   BUILDER = 'builder',
+  ANNOTATION = 'annotation',
 }
 
-export interface MCQueryBase extends DataQuery {}
+export interface MCQueryBase extends DataQuery {
+  timezone?: string;
+  variables?: Record<string, MacroVariable>;
+  timeShift?: string;
+  flattenStructs?: boolean;
+  explode?: string;
+  decimalMode?: DecimalMode;
+  intervalMode?: IntervalMode;
+  binaryMode?: BinaryMode;
+  columnComments?: boolean;
+  logs?: LogsColumns;
+  logsVolume?: boolean;
+  trace?: TraceColumns;
+  traceId?: string;
+  splitBy?: string[];
+  maxSeries?: number;
+  annotations?: AnnotationColumns;
+}
+
+/**
+ * Columns read as the time, end time, title, text and tags of annotations, detected by name when unset
+ */
+export interface AnnotationColumns {
+  timeColumn?: string;
+  timeEndColumn?: string;
+  titleColumn?: string;
+  textColumn?: string;
+  tagsColumn?: string;
+}
+
+/**
+ * Columns read as the time, body and level of log lines, detected by name when unset
+ */
+export interface LogsColumns {
+  timeColumn?: string;
+  bodyColumn?: string;
+  levelColumn?: string;
+}
+
+/**
+ * Columns read as the spans of a trace, detected by name when unset
+ */
+export interface TraceColumns {
+  traceIdColumn?: string;
+  spanIdColumn?: string;
+  parentSpanIdColumn?: string;
+  operationNameColumn?: string;
+  serviceNameColumn?: string;
+  startTimeColumn?: string;
+  durationColumn?: string;
+  serviceTagsColumn?: string;
+  tagsColumns?: string[];
+  timeUnit?: TimeUnit;
+}
+
+/**
+ * Unit of span durations and of numeric span start times
+ */
+export enum TimeUnit {
+  SECONDS = 's',
+  MILLISECONDS = 'ms',
+  MICROSECONDS = 'us',
+  NANOSECONDS = 'ns',
+}
+
+/**
+ * Raw template variable values read by the `$__in` and `$__values` macros
+ */
+export interface MacroVariable {
+  values: string[];
+  all?: boolean;
+}
+
+/**
+ * How DECIMAL values are returned: exact strings, float64 or int64 scaled by the declared scale
+ */
+export enum DecimalMode {
+  STRING = 'string',
+  FLOAT = 'float',
+  SCALED = 'scaled',
+}
+
+/**
+ * How INTERVAL values are returned: text, or milliseconds and months
+ */
+export enum IntervalMode {
+  STRING = 'string',
+  NUMERIC = 'numeric',
+}
+
+/**
+ * How BINARY values are returned: base64, hex, UTF-8 text or their length in bytes
+ */
+export enum BinaryMode {
+  BASE64 = 'base64',
+  HEX = 'hex',
+  UTF8 = 'utf8',
+  LENGTH = 'length',
+}
 
 export enum Format {
   TIMESERIES = 0,
@@ -34,7 +133,15 @@ export interface MCBuilderQuery extends MCQueryBase {
   selectedFormat: Format;
 }
 
-export type MCQuery = MCSQLQuery | MCBuilderQuery;
+export interface MCAnnotationQuery extends MCQueryBase {
+  queryType: QueryType.ANNOTATION;
+  rawSql: string;
+
+  format: Format;
+  selectedFormat: Format;
+}
+
+export type MCQuery = MCSQLQuery | MCBuilderQuery | MCAnnotationQuery;
 
 // TODO: add query builder support later...
 
@@ -52,10 +159,43 @@ export interface MCConfig extends DataSourceJsonData {
   httpTimeout?: number;
   tunnelEndpoint?: string;
   tunnelQuotaName?: string;
+  timezone?: string;
+  macros?: CustomMacro[];
+  nestedTypesAsString?: boolean;
+  decimalMode?: DecimalMode;
+  intervalMode?: IntervalMode;
+  timestampNtzTimezone?: string;
+  maxSeries?: number;
 
   others?: CustomOption[];
 }
 
+/**
+ * Macro metadata served by the backend `macros` resource
+ */
+export interface MacroArgument {
+  name: string;
+  description: string;
+  optional?: boolean;
+  values?: string[];
+}
+
+export interface MacroInfo {
+  name: string;
+  description: string;
+  minArgs: number;
+  maxArgs: number;
+  args: MacroArgument[];
+  example: string;
+}
+
+export interface CustomMacro {
+  name: string;
+  params: string[];
+  body: string;
+  description?: string;
+}
+
 export interface CustomOption {
   key: string;
   value: string;
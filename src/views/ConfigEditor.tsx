@@ -1,7 +1,7 @@
 import React, { ChangeEvent, useMemo, useState } from 'react';
-import { Button, Field, HorizontalGroup, Input, SecretInput } from '@grafana/ui';
-import { DataSourcePluginOptionsEditorProps, onUpdateDatasourceJsonDataOption, onUpdateDatasourceSecureJsonDataOption } from '@grafana/data';
-import { CustomOption, MCConfig, MCSecureConfig } from '../types';
+import { Button, Field, HorizontalGroup, Input, SecretInput, Select, Switch } from '@grafana/ui';
+import { DataSourcePluginOptionsEditorProps, onUpdateDatasourceJsonDataOption, onUpdateDatasourceJsonDataOptionChecked, onUpdateDatasourceSecureJsonDataOption } from '@grafana/data';
+import { CustomMacro, CustomOption, DecimalMode, IntervalMode, MCConfig, MCSecureConfig } from '../types';
 import { ConfigSection, ConfigSubSection, DataSourceDescription } from '@grafana/experimental';
 import { Divider } from 'components/Divider';
 import { Components } from 'selectors';
@@ -20,12 +20,20 @@ export function MCConfigEditor(props: Props) {
         options.jsonData.httpTimeout ||
         options.jsonData.tunnelEndpoint ||
         options.jsonData.tunnelQuotaName ||
+        options.jsonData.timezone ||
+        options.jsonData.timestampNtzTimezone ||
+        options.jsonData.maxSeries ||
+        options.jsonData.nestedTypesAsString ||
+        options.jsonData.decimalMode ||
+        options.jsonData.intervalMode ||
+        (options.jsonData.macros && options.jsonData.macros.length !== 0) ||
         (options.jsonData.others && options.jsonData.others.length !== 0)
       ),
     [options]
   );
 
   const [otherOptions, setOtherOptions] = useState(jsonData.others || []);
+  const [customMacros, setCustomMacros] = useState(jsonData.macros || []);
 
   const onResetAccessKeySecret = () => {
     onOptionsChange({
@@ -55,6 +63,17 @@ export function MCConfigEditor(props: Props) {
     });
   };
 
+  const onMaxSeriesChange = (e: ChangeEvent<HTMLInputElement>) => {
+    const maxSeries = parseInt(e.currentTarget.value, 10);
+    onOptionsChange({
+      ...options,
+      jsonData: {
+        ...options.jsonData,
+        maxSeries: maxSeries > 0 ? maxSeries : undefined,
+      },
+    });
+  };
+
   const onOtherOptionsChange = (otherOptions: CustomOption[]) => {
     onOptionsChange({
       ...options,
@@ -66,6 +85,16 @@ export function MCConfigEditor(props: Props) {
   }
 
 
+  const onCustomMacrosChange = (customMacros: CustomMacro[]) => {
+    onOptionsChange({
+      ...options,
+      jsonData: {
+        ...options.jsonData,
+        macros: customMacros.filter((m) => !!m.name && !!m.body),
+      }
+    })
+  }
+
   const isValidUrl = /^(http|https):\/\/(\w+:{0,1}\w*@)?(\S+)(:[0-9]+)?(\/|\/([\w#!:.?+=&%@!\-\/]))?$/.test(
     jsonData.endpoint
   );
@@ -244,6 +273,147 @@ export function MCConfigEditor(props: Props) {
           />
         </Field>
 
+        <Field
+          label={Components.ConfigEditor.Timezone.label}
+          description={Components.ConfigEditor.Timezone.tooltip}
+        >
+          <Input
+            name="timezone"
+            width={40}
+            value={jsonData.timezone || ''}
+            onChange={onUpdateDatasourceJsonDataOption(props, 'timezone')}
+            label={Components.ConfigEditor.Timezone.label}
+            aria-label={Components.ConfigEditor.Timezone.label}
+            placeholder={Components.ConfigEditor.Timezone.placeholder}
+          />
+        </Field>
+
+        <Field
+          label={Components.ConfigEditor.TimestampNtzTimezone.label}
+          description={Components.ConfigEditor.TimestampNtzTimezone.tooltip}
+        >
+          <Input
+            name="timestampNtzTimezone"
+            width={40}
+            value={jsonData.timestampNtzTimezone || ''}
+            onChange={onUpdateDatasourceJsonDataOption(props, 'timestampNtzTimezone')}
+            label={Components.ConfigEditor.TimestampNtzTimezone.label}
+            aria-label={Components.ConfigEditor.TimestampNtzTimezone.label}
+            placeholder={Components.ConfigEditor.TimestampNtzTimezone.placeholder}
+          />
+        </Field>
+
+        <Field
+          label={Components.ConfigEditor.MaxSeries.label}
+          description={Components.ConfigEditor.MaxSeries.tooltip}
+        >
+          <Input
+            name="maxSeries"
+            width={40}
+            value={jsonData.maxSeries || ''}
+            onChange={onMaxSeriesChange}
+            label={Components.ConfigEditor.MaxSeries.label}
+            aria-label={Components.ConfigEditor.MaxSeries.label}
+            placeholder={Components.ConfigEditor.MaxSeries.placeholder}
+            type='number'
+          />
+        </Field>
+
+        <Field
+          label={Components.ConfigEditor.NestedTypesAsString.label}
+          description={Components.ConfigEditor.NestedTypesAsString.tooltip}
+        >
+          <Switch
+            value={jsonData.nestedTypesAsString || false}
+            onChange={onUpdateDatasourceJsonDataOptionChecked(props, 'nestedTypesAsString')}
+            aria-label={Components.ConfigEditor.NestedTypesAsString.label}
+          />
+        </Field>
+
+        <Field
+          label={Components.ConfigEditor.DecimalMode.label}
+          description={Components.ConfigEditor.DecimalMode.tooltip}
+        >
+          <Select
+            width={40}
+            value={jsonData.decimalMode || DecimalMode.STRING}
+            options={Object.values(DecimalMode).map((mode) => ({
+              label: Components.ConfigEditor.DecimalMode.options[mode],
+              value: mode,
+            }))}
+            onChange={(e) => onOptionsChange({ ...options, jsonData: { ...jsonData, decimalMode: e.value } })}
+            aria-label={Components.ConfigEditor.DecimalMode.label}
+          />
+        </Field>
+
+        <Field
+          label={Components.ConfigEditor.IntervalMode.label}
+          description={Components.ConfigEditor.IntervalMode.tooltip}
+        >
+          <Select
+            width={40}
+            value={jsonData.intervalMode || IntervalMode.STRING}
+            options={Object.values(IntervalMode).map((mode) => ({
+              label: Components.ConfigEditor.IntervalMode.options[mode],
+              value: mode,
+            }))}
+            onChange={(e) => onOptionsChange({ ...options, jsonData: { ...jsonData, intervalMode: e.value } })}
+            aria-label={Components.ConfigEditor.IntervalMode.label}
+          />
+        </Field>
+
+        <ConfigSubSection title={Components.ConfigEditor.CustomMacros.title}>
+          {customMacros.map((macro, i) => {
+            const update = (m: Partial<CustomMacro>) => {
+              let newMacros = customMacros.concat();
+              newMacros[i] = { ...macro, ...m };
+              setCustomMacros(newMacros);
+            };
+            return (
+              <HorizontalGroup key={i}>
+                <Field label={Components.ConfigEditor.CustomMacros.name}>
+                  <Input
+                    value={macro.name}
+                    placeholder={'regionFilter'}
+                    onChange={(e: ChangeEvent<HTMLInputElement>) => update({ name: e.target.value })}
+                    onBlur={() => onCustomMacrosChange(customMacros)}
+                  ></Input>
+                </Field>
+                <Field label={Components.ConfigEditor.CustomMacros.params}>
+                  <Input
+                    value={macro.params.join(', ')}
+                    placeholder={Components.ConfigEditor.CustomMacros.paramsPlaceholder}
+                    onChange={(e: ChangeEvent<HTMLInputElement>) =>
+                      update({ params: e.target.value.split(',').map((p) => p.trim()).filter((p) => !!p) })
+                    }
+                    onBlur={() => onCustomMacrosChange(customMacros)}
+                  ></Input>
+                </Field>
+                <Field label={Components.ConfigEditor.CustomMacros.body}>
+                  <Input
+                    width={60}
+                    value={macro.body}
+                    placeholder={Components.ConfigEditor.CustomMacros.bodyPlaceholder}
+                    onChange={(e: ChangeEvent<HTMLInputElement>) => update({ body: e.target.value })}
+                    onBlur={() => onCustomMacrosChange(customMacros)}
+                  ></Input>
+                </Field>
+              </HorizontalGroup>
+            );
+          })}
+
+          <Button
+            variant="secondary"
+            icon="plus"
+            type="button"
+            onClick={() => {
+              setCustomMacros([...customMacros, { name: '', params: [], body: '' }])
+            }}
+          >
+            Add custom macro
+          </Button>
+        </ConfigSubSection>
+
         <ConfigSubSection title="Hints and Other Options">
           {otherOptions.map(({ key, value }, i) => {
             return (